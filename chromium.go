@@ -0,0 +1,283 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1"
+	"crypto/sha256"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/errgo.v1"
+)
+
+// chromiumEpochOffset holds the number of seconds between
+// 1601-01-01, which Chromium measures times from, and the
+// Unix epoch.
+const chromiumEpochOffset = 11644473600
+
+// chromiumV10Password is the password that Chromium on Linux uses to
+// encrypt cookie values when no keyring is available.
+const chromiumV10Password = "peanuts"
+
+// ImportChromium merges the cookies from the given Chromium or Google
+// Chrome Cookies database, as found on Linux, into the jar. Like
+// ImportFirefox, it only ever reads the file.
+//
+// Cookie values encrypted with the "v10" scheme are decrypted
+// automatically. Values encrypted with the "v11" scheme need the
+// password that the browser keeps in the desktop keyring (for example
+// under "Chrome Safe Storage"), which should be supplied as password;
+// if it is nil and such a value is found, an error is returned.
+//
+// As for cookies merged from storage, a cookie replaces one already in
+// the jar only if it was updated in the browser more recently than
+// the jar's cookie.
+func (j *Jar) ImportChromium(filename string, password []byte) error {
+	return j.importChromium(filename, password, time.Now())
+}
+
+// importChromium is like ImportChromium but takes the current time as a
+// parameter.
+func (j *Jar) importChromium(filename string, password []byte, now time.Time) error {
+	db, err := openSQLite(filename)
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	version, err := chromiumVersion(db)
+	if err != nil {
+		return errgo.Notef(err, "cannot read Chromium cookies")
+	}
+	t, err := db.table("cookies")
+	if err != nil {
+		return errgo.Notef(err, "cannot read Chromium cookies")
+	}
+	col := make(map[string]int)
+	for _, name := range []string{
+		"creation_utc",
+		"host_key",
+		"top_frame_site_key",
+		"name",
+		"value",
+		"encrypted_value",
+		"path",
+		"expires_utc",
+		"is_secure",
+		"is_httponly",
+		"last_access_utc",
+		"is_persistent",
+		"samesite",
+		"last_update_utc",
+	} {
+		col[name] = t.column(name)
+	}
+	for _, name := range []string{"host_key", "name", "value", "path", "expires_utc"} {
+		if col[name] < 0 {
+			return errgo.Newf("cannot read Chromium cookies: no %s column", name)
+		}
+	}
+	d := &chromiumDecrypter{
+		password: password,
+		version:  version,
+	}
+	var entries []entry
+	for _, row := range t.rows {
+		e, ok, err := j.chromiumEntry(func(name string) interface{} {
+			if i := col[name]; i >= 0 {
+				return row[i]
+			}
+			return nil
+		}, d, now)
+		if err != nil {
+			return errgo.Notef(err, "cannot read Chromium cookies")
+		}
+		if ok {
+			entries = append(entries, e)
+		}
+	}
+	defer j.notify()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.merge(entries) {
+		j.changed()
+		j.evict(now)
+	}
+	return nil
+}
+
+// chromiumVersion returns the version of the schema
+// of the given Chromium cookie database.
+func chromiumVersion(db *sqliteDB) (int, error) {
+	t, err := db.table("meta")
+	if err != nil {
+		return 0, errgo.Mask(err)
+	}
+	key, value := t.column("key"), t.column("value")
+	if key < 0 || value < 0 {
+		return 0, errgo.New("invalid meta table")
+	}
+	for _, row := range t.rows {
+		if sqliteString(row[key]) != "version" {
+			continue
+		}
+		if v, ok := row[value].(int64); ok {
+			return int(v), nil
+		}
+		v, err := strconv.Atoi(sqliteString(row[value]))
+		if err != nil {
+			return 0, errgo.Newf("invalid version %q", row[value])
+		}
+		return v, nil
+	}
+	return 0, errgo.New("no version found")
+}
+
+// chromiumEntry returns the entry for the cookies row with the given
+// values. It returns false if the cookie should be ignored.
+func (j *Jar) chromiumEntry(value func(column string) interface{}, d *chromiumDecrypter, now time.Time) (entry, bool, error) {
+	var e entry
+	hostKey := sqliteString(value("host_key"))
+	if err := j.setImportedDomain(&e, hostKey, !strings.HasPrefix(hostKey, ".")); err != nil {
+		return e, false, nil
+	}
+	e.Name = sqliteString(value("name"))
+	e.Value = sqliteString(value("value"))
+	if encrypted, _ := value("encrypted_value").([]byte); len(encrypted) > 0 {
+		decrypted, err := d.decrypt(encrypted, hostKey)
+		if err != nil {
+			return e, false, errgo.Notef(err, "cannot decrypt value of cookie %q for %q", e.Name, hostKey)
+		}
+		e.Value = decrypted
+	}
+	e.Path = sqliteString(value("path"))
+	if e.Path == "" || e.Path[0] != '/' {
+		e.Path = "/"
+	}
+	e.Secure = sqliteInt64(value("is_secure")) != 0
+	e.HttpOnly = sqliteInt64(value("is_httponly")) != 0
+	switch sqliteInt64(value("samesite")) {
+	case 0:
+		e.SameSite = "None"
+	case 1:
+		e.SameSite = "Lax"
+	case 2:
+		e.SameSite = "Strict"
+	}
+	e.PartitionKey = sqliteString(value("top_frame_site_key"))
+	// Old databases have no is_persistent column,
+	// and hold only persistent cookies.
+	if v := value("is_persistent"); v == nil || sqliteInt64(v) != 0 {
+		e.Persistent = true
+		e.Expires = chromiumTime(value("expires_utc"), time.Time{})
+		if !e.Expires.After(now) {
+			return e, false, nil
+		}
+	} else {
+		e.Expires = endOfTime
+	}
+	e.Creation = chromiumTime(value("creation_utc"), now)
+	e.LastAccess = chromiumTime(value("last_access_utc"), e.Creation)
+	e.Updated = chromiumTime(value("last_update_utc"), e.Creation)
+	return e, true, nil
+}
+
+// chromiumTime returns the time held in microseconds since
+// 1601-01-01 in the given value, or def if there is none.
+func chromiumTime(v interface{}, def time.Time) time.Time {
+	t := sqliteInt64(v)
+	if t <= 0 {
+		return def
+	}
+	return time.Unix(t/1e6-chromiumEpochOffset, t%1e6*1e3).UTC()
+}
+
+// chromiumDecrypter decrypts the cookie values
+// in a Chromium cookie database from Linux.
+type chromiumDecrypter struct {
+	// password holds the password for v11 values.
+	password []byte
+
+	// version holds the version of the database schema.
+	version int
+
+	// v10Key and v11Key cache the keys derived
+	// from the passwords.
+	v10Key, v11Key []byte
+}
+
+// decrypt decrypts the given encrypted value of
+// a cookie with the given host key.
+func (d *chromiumDecrypter) decrypt(encrypted []byte, hostKey string) (string, error) {
+	var key []byte
+	switch {
+	case bytes.HasPrefix(encrypted, []byte("v10")):
+		if d.v10Key == nil {
+			d.v10Key = chromiumKey([]byte(chromiumV10Password))
+		}
+		key = d.v10Key
+	case bytes.HasPrefix(encrypted, []byte("v11")):
+		if d.password == nil {
+			return "", errgo.New("value encrypted with keyring password but no password supplied")
+		}
+		if d.v11Key == nil {
+			d.v11Key = chromiumKey(d.password)
+		}
+		key = d.v11Key
+	default:
+		return "", errgo.New("unsupported encryption scheme")
+	}
+	ciphertext := encrypted[3:]
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return "", errgo.New("invalid encrypted value length")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", errgo.Mask(err)
+	}
+	iv := bytes.Repeat([]byte{' '}, aes.BlockSize)
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+	plaintext, ok := unpad(plaintext)
+	if !ok {
+		return "", errgo.New("invalid padding (wrong password?)")
+	}
+	if d.version >= 24 {
+		// Since version 24, the value is prefixed with
+		// the SHA-256 hash of the cookie's host key.
+		hash := sha256.Sum256([]byte(hostKey))
+		if len(plaintext) < len(hash) || !bytes.Equal(plaintext[:len(hash)], hash[:]) {
+			return "", errgo.New("decrypted value does not match host (wrong password?)")
+		}
+		plaintext = plaintext[len(hash):]
+	}
+	return string(plaintext), nil
+}
+
+// chromiumKey returns the AES key that Chromium on Linux
+// derives from the given password.
+func chromiumKey(password []byte) []byte {
+	return pbkdf2Key(sha1.New, password, []byte("saltysalt"), 1, 16)
+}
+
+// unpad removes PKCS #7 padding from data.
+func unpad(data []byte) ([]byte, bool) {
+	if len(data) == 0 {
+		return nil, false
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > aes.BlockSize || n > len(data) {
+		return nil, false
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, false
+		}
+	}
+	return data[:len(data)-n], true
+}
@@ -0,0 +1,120 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// The Chromium fixtures in testdata are generated by mkchromium.py.
+
+const chromiumTestPassword = "keyring-secret"
+
+func TestImportChromium(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	err := jar.importChromium("testdata/chromium/Cookies", []byte(chromiumTestPassword), firefoxNow)
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(jar.dirty, qt.Equals, true)
+
+	created := time.Unix(1700000000, 0).UTC()
+	accessed := time.Unix(1730000000, 0).UTC()
+	updated := time.Unix(1720000000, 0).UTC()
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.Assert(jar.entries["example.com"], qt.DeepEquals, map[string]entry{
+		"www.example.com;/;plain": {
+			Name:          "plain",
+			Value:         "p",
+			Domain:        "www.example.com",
+			Path:          "/",
+			Secure:        true,
+			HttpOnly:      true,
+			Persistent:    true,
+			HostOnly:      true,
+			Expires:       expires,
+			Creation:      created,
+			LastAccess:    accessed,
+			Updated:       updated,
+			CanonicalHost: "www.example.com",
+			SameSite:      "Strict",
+		},
+		"example.com;/app;v10": {
+			Name:          "v10",
+			Value:         "ten",
+			Domain:        "example.com",
+			Path:          "/app",
+			Persistent:    true,
+			Expires:       expires,
+			Creation:      created,
+			LastAccess:    accessed,
+			Updated:       updated,
+			CanonicalHost: "example.com",
+			SameSite:      "Lax",
+		},
+		"www.example.com;/;v11": {
+			Name:          "v11",
+			Value:         "eleven",
+			Domain:        "www.example.com",
+			Path:          "/",
+			Secure:        true,
+			Persistent:    true,
+			HostOnly:      true,
+			Expires:       expires,
+			Creation:      created,
+			LastAccess:    accessed,
+			Updated:       updated,
+			CanonicalHost: "www.example.com",
+			SameSite:      "None",
+		},
+		"www.example.com;/;session": {
+			Name:          "session",
+			Value:         "s",
+			Domain:        "www.example.com",
+			Path:          "/",
+			HostOnly:      true,
+			Expires:       endOfTime,
+			Creation:      created,
+			LastAccess:    accessed,
+			Updated:       updated,
+			CanonicalHost: "www.example.com",
+		},
+	})
+	cookies := jar.cookiesForRequest(mustParseURL("https://embed.test/"), mustParseURL("https://www.site.test/"), "", firefoxNow)
+	c.Assert(cookies, qt.DeepEquals, []*http.Cookie{{Name: "partitioned", Value: "pt"}})
+	c.Assert(queryJar(jar, "https://embed.test/", firefoxNow), qt.Equals, "")
+}
+
+func TestImportChromiumOldVersion(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	err := jar.importChromium("testdata/chromium-v10/Cookies", nil, firefoxNow)
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(queryJar(jar, "https://www.example.com/app", firefoxNow), qt.Equals, "v10=ten plain=p session=s")
+}
+
+func TestImportChromiumNoPassword(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	err := jar.importChromium("testdata/chromium/Cookies", nil, firefoxNow)
+	c.Assert(err, qt.ErrorMatches, `cannot read Chromium cookies: cannot decrypt value of cookie "v11" for "www.example.com": value encrypted with keyring password but no password supplied`)
+	c.Assert(allCookies(jar, firefoxNow), qt.Equals, "")
+}
+
+func TestImportChromiumWrongPassword(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	err := jar.importChromium("testdata/chromium/Cookies", []byte("wrong"), firefoxNow)
+	c.Assert(err, qt.ErrorMatches, `cannot read Chromium cookies: cannot decrypt value of cookie "v11" for "www.example.com": (invalid padding|decrypted value does not match host) \(wrong password\?\)`)
+}
+
+func TestChromiumTime(t *testing.T) {
+	c := qt.New(t)
+	c.Assert(chromiumTime(int64(13253932800000000), time.Time{}), qt.Equals, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	c.Assert(chromiumTime(int64(0), tNow), qt.Equals, tNow)
+}
@@ -0,0 +1,150 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Clone returns a deep copy of the jar. The copy holds the same
+// cookies and uses the same options as j except that it is not
+// persisted and has no subscribers. Changes to either jar do not
+// affect the other; use Merge to combine them again.
+func (j *Jar) Clone() *Jar {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return &Jar{
+		psList:             j.psList,
+		maxPerDomain:       j.maxPerDomain,
+		maxCookies:         j.maxCookies,
+		policy:             j.policy,
+		persistSession:     j.persistSession,
+		maxSessionLifetime: j.maxSessionLifetime,
+		entries:            j.copyEntries(),
+	}
+}
+
+// Merge merges all the cookies in other into j, including cookies
+// that have been removed from other. As when merging cookies from
+// storage, more recently changed cookies take precedence over older
+// ones.
+func (j *Jar) Merge(other *Jar) {
+	j.mergeJar(other, time.Now())
+}
+
+// mergeJar is like Merge but takes the current time as a parameter.
+func (j *Jar) mergeJar(other *Jar, now time.Time) {
+	if other == j {
+		return
+	}
+	// Take a copy of the other jar's entries first so that
+	// we never hold both locks at once.
+	other.mu.Lock()
+	var entries []entry
+	for _, submap := range other.entries {
+		for _, e := range submap {
+			entries = append(entries, e)
+		}
+	}
+	other.mu.Unlock()
+
+	defer j.notify()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.merge(entries) {
+		j.changed()
+		j.evict(now)
+	}
+}
+
+// copyEntries returns a copy of j.entries.
+// It must be called with j.mu held.
+func (j *Jar) copyEntries() map[string]map[string]entry {
+	entries := make(map[string]map[string]entry, len(j.entries))
+	for key, submap := range j.entries {
+		m := make(map[string]entry, len(submap))
+		for id, e := range submap {
+			m[id] = e
+		}
+		entries[key] = m
+	}
+	return entries
+}
+
+// Snapshot holds an immutable copy of the cookies in a jar
+// at a moment in time. It is safe to use concurrently
+// and never changes, even when the jar does.
+type Snapshot struct {
+	psList  PublicSuffixList
+	entries map[string]map[string]entry
+}
+
+// Snapshot returns a snapshot of the cookies currently in the jar.
+func (j *Jar) Snapshot() *Snapshot {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return &Snapshot{
+		psList:  j.psList,
+		entries: j.copyEntries(),
+	}
+}
+
+// Cookies returns the cookies that the jar would have returned from
+// Cookies(u) when the snapshot was taken, except that cookies that
+// have expired since then are omitted. Unlike Jar.Cookies, it
+// does not record the time that the cookies were accessed.
+func (s *Snapshot) Cookies(u *url.URL) []*http.Cookie {
+	return s.cookies(u, time.Now())
+}
+
+// cookies is like Cookies but takes the current time as a parameter.
+func (s *Snapshot) cookies(u *url.URL, now time.Time) []*http.Cookie {
+	return matchingCookies(s.entries, s.psList, u, now)
+}
+
+// matchingCookies returns the cookies in the given entries that should
+// be sent to u at the given time, without modifying the entries.
+func matchingCookies(entries map[string]map[string]entry, psList PublicSuffixList, u *url.URL, now time.Time) (cookies []*http.Cookie) {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return cookies
+	}
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return cookies
+	}
+	key := jarKey(host, psList)
+	site := u.Scheme + "://" + key
+	https := u.Scheme == "https"
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	var selected []entry
+	for _, e := range entries[key] {
+		if e.Expires.After(now) && e.shouldSend(https, host, path, site) {
+			selected = append(selected, e)
+		}
+	}
+	sort.Sort(byPathLength(selected))
+	for _, e := range selected {
+		cookies = append(cookies, &http.Cookie{Name: e.Name, Value: e.Value})
+	}
+	return cookies
+}
+
+// AllCookies is like Jar.AllCookies but returns the
+// cookies in the snapshot.
+func (s *Snapshot) AllCookies() []*http.Cookie {
+	return liveCookies(s.entries, time.Now())
+}
+
+// Entries is like Jar.Entries but returns the
+// entries in the snapshot.
+func (s *Snapshot) Entries(includeTombstones bool) []Entry {
+	return allEntries(s.entries, includeTombstones, time.Now())
+}
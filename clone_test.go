@@ -0,0 +1,101 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestClone(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	jar := newStorageJar(c, storage)
+	var r eventRecorder
+	jar.Subscribe(r.record)
+	setCookies(jar, "http://www.host.test/", []string{"a=1", "b=1"}, tNow)
+	r.take()
+
+	clone := jar.Clone()
+	c.Assert(clone.entries, qt.DeepEquals, jar.entries)
+	setCookies(clone, "http://www.host.test/", []string{"a=2", "c=1"}, atTime(1))
+	c.Assert(allCookies(clone, atTime(1)), qt.Equals, "a=2 b=1 c=1")
+	c.Assert(allCookies(jar, atTime(1)), qt.Equals, "a=1 b=1")
+
+	// The clone has no subscribers and is not persisted.
+	c.Assert(r.take(), qt.Equals, "")
+	err := clone.Save()
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(storage.storeCount(), qt.Equals, 0)
+}
+
+func TestMerge(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	setCookies(jar, "http://www.host.test/", []string{"a=1", "b=1", "c=1"}, tNow)
+	worker := jar.Clone()
+	setCookies(worker, "http://www.host.test/", []string{"a=2", "b=; max-age=-1", "d=1"}, atTime(1))
+	setCookies(jar, "http://www.host.test/", []string{"c=2"}, atTime(2))
+	setCookies(worker, "http://www.host.test/", []string{"c=3"}, atTime(1))
+
+	var r eventRecorder
+	jar.Subscribe(r.record)
+	jar.mergeJar(worker, atTime(3))
+	c.Assert(allCookies(jar, atTime(3)), qt.Equals, "a=2 c=2 d=1")
+	c.Assert(allCookiesIncludingExpired(jar, atTime(3)), qt.Equals, "a=2 b= c=2 d=1")
+	c.Assert(len(r.events), qt.Equals, 3)
+	c.Assert(jar.dirty, qt.Equals, true)
+
+	// Merging with itself does nothing.
+	jar.mergeJar(jar, atTime(3))
+	c.Assert(allCookies(jar, atTime(3)), qt.Equals, "a=2 c=2 d=1")
+}
+
+func TestMergeJarConcurrent(t *testing.T) {
+	jar := newTestJar("")
+	setCookies(jar, "http://www.host.test/", []string{"a=1"}, tNow)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker := jar.Clone()
+			worker.Merge(jar)
+			jar.Merge(worker)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSnapshot(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	setCookies(jar, "https://www.host.test/foo/", []string{
+		"a=1",
+		"b=1; path=/; secure",
+		"c=1; max-age=10",
+	}, tNow)
+	snapshot := jar.Snapshot()
+	before := jar.allEntries(true, tNow)
+	setCookies(jar, "https://www.host.test/foo/", []string{"a=2", "d=1"}, atTime(1))
+
+	var names []string
+	for _, cookie := range snapshot.cookies(mustParseURL("https://www.host.test/foo/x"), atTime(5)) {
+		names = append(names, cookie.Name+"="+cookie.Value)
+	}
+	c.Assert(names, qt.DeepEquals, []string{"a=1", "c=1", "b=1"})
+	names = nil
+	for _, cookie := range snapshot.cookies(mustParseURL("http://www.host.test/"), atTime(20)) {
+		names = append(names, cookie.Name+"="+cookie.Value)
+	}
+	c.Assert(names, qt.DeepEquals, []string(nil))
+
+	// Querying the snapshot does not change it.
+	c.Assert(allEntries(snapshot.entries, true, tNow), qt.DeepEquals, before)
+	c.Assert(len(snapshot.AllCookies()), qt.Equals, 2)
+	c.Assert(len(snapshot.Entries(true)), qt.Equals, 3)
+}
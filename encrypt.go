@@ -0,0 +1,193 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+
+	"gopkg.in/errgo.v1"
+)
+
+// Encrypted cookie data has the following format:
+//
+//	magic       encryptedMagic
+//	salt        [encryptedSaltSize]byte
+//	nonce       [12]byte
+//	ciphertext  the JSON data sealed with AES-256-GCM
+//
+// The magic and salt are authenticated as additional data, so
+// that they cannot be changed without the change being detected.
+
+// encryptedMagic is the start of all encrypted cookie data. Unencrypted
+// data is JSON, so can never start with it.
+const encryptedMagic = "PCJ-AES256GCM-1\n"
+
+// encryptedSaltSize holds the size of the salt passed to
+// KeyProvider.Key.
+const encryptedSaltSize = 16
+
+// encryptionKeySize holds the size of the keys
+// returned by KeyProvider.Key.
+const encryptionKeySize = 32
+
+// passphraseIterations holds the number of PBKDF2 iterations used to
+// derive keys from a passphrase. It is a variable so that tests can
+// make it smaller.
+var passphraseIterations = 600000
+
+// KeyProvider provides the key used to encrypt the cookies
+// in a jar's storage. See Options.Encryption.
+type KeyProvider interface {
+	// Key returns the 32-byte AES-256 key to use for cookie data
+	// stored with the given salt. A new random salt is used when
+	// a jar first stores encrypted data; thereafter the salt
+	// stays the same, so Key is called only once or twice for
+	// each jar.
+	Key(salt []byte) ([]byte, error)
+}
+
+// KeyFunc implements KeyProvider by calling the function itself. It can
+// be used to fetch the key from an operating system keyring or a
+// secrets manager, for example.
+type KeyFunc func(salt []byte) ([]byte, error)
+
+// Key implements KeyProvider.Key.
+func (f KeyFunc) Key(salt []byte) ([]byte, error) {
+	return f(salt)
+}
+
+// StaticKey returns a KeyProvider that always returns the given key,
+// which must be 32 bytes long. The salt is not used.
+func StaticKey(key []byte) KeyProvider {
+	key = append([]byte(nil), key...)
+	return KeyFunc(func(salt []byte) ([]byte, error) {
+		return key, nil
+	})
+}
+
+// PassphraseFile returns a KeyProvider that derives keys from the
+// passphrase held in the named file, using PBKDF2 with SHA-256 and the
+// salt. Any trailing newline in the file is ignored. The file is read
+// each time a key is derived, so it should be kept as private as the
+// cookies themselves.
+func PassphraseFile(filename string) KeyProvider {
+	return KeyFunc(func(salt []byte) ([]byte, error) {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, errgo.Notef(err, "cannot read passphrase")
+		}
+		passphrase := bytes.TrimRight(data, "\r\n")
+		if len(passphrase) == 0 {
+			return nil, errgo.Newf("passphrase file %q is empty", filename)
+		}
+		return pbkdf2Key(sha256.New, passphrase, salt, passphraseIterations, encryptionKeySize), nil
+	})
+}
+
+// decodeStored returns the cookie data held in data, which was loaded
+// from j.storage, decrypting it if needed. If the jar encrypts its
+// storage but the data is not encrypted, the jar is marked as changed
+// so that the data will be encrypted when it is next saved. It must be
+// called with j.mu held.
+func (j *Jar) decodeStored(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(encryptedMagic)) {
+		if j.keys != nil && len(bytes.TrimSpace(data)) > 0 {
+			j.changed()
+		}
+		return data, nil
+	}
+	if j.keys == nil {
+		return nil, errgo.New("cookies are encrypted but no key was provided")
+	}
+	headerSize := len(encryptedMagic) + encryptedSaltSize
+	if len(data) < headerSize {
+		return nil, errgo.New("encrypted cookies are truncated")
+	}
+	header := data[:headerSize]
+	salt := header[len(encryptedMagic):]
+	key := j.key
+	if !bytes.Equal(salt, j.salt) {
+		var err error
+		key, err = j.encryptionKey(salt)
+		if err != nil {
+			return nil, errgo.Mask(err)
+		}
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	data = data[headerSize:]
+	if len(data) < aead.NonceSize() {
+		return nil, errgo.New("encrypted cookies are truncated")
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], header)
+	if err != nil {
+		return nil, errgo.New("cannot decrypt cookies (wrong key?)")
+	}
+	j.salt = append([]byte(nil), salt...)
+	j.key = key
+	return plaintext, nil
+}
+
+// encodeStored returns the data to store in j.storage for the given
+// cookie data, encrypting it if needed. It must be called with j.mu
+// held.
+func (j *Jar) encodeStored(data []byte) ([]byte, error) {
+	if j.keys == nil {
+		return data, nil
+	}
+	if j.key == nil {
+		salt := make([]byte, encryptedSaltSize)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, errgo.Mask(err)
+		}
+		key, err := j.encryptionKey(salt)
+		if err != nil {
+			return nil, errgo.Mask(err)
+		}
+		j.salt, j.key = salt, key
+	}
+	aead, err := newAEAD(j.key)
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	header := append([]byte(encryptedMagic), j.salt...)
+	result := make([]byte, len(header)+aead.NonceSize(), len(header)+aead.NonceSize()+len(data)+aead.Overhead())
+	copy(result, header)
+	nonce := result[len(header):]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errgo.Mask(err)
+	}
+	return aead.Seal(result, nonce, data, header), nil
+}
+
+// encryptionKey returns the key for the given salt
+// from j.keys.
+func (j *Jar) encryptionKey(salt []byte) ([]byte, error) {
+	key, err := j.keys.Key(salt)
+	if err != nil {
+		return nil, errgo.Notef(err, "cannot get encryption key")
+	}
+	if len(key) != encryptionKeySize {
+		return nil, errgo.Newf("encryption key is %d bytes long; need %d", len(key), encryptionKeySize)
+	}
+	return key, nil
+}
+
+// newAEAD returns AES-256-GCM with the given key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,230 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+var (
+	testKey0 = bytes.Repeat([]byte{1}, 32)
+	testKey1 = bytes.Repeat([]byte{2}, 32)
+)
+
+func newEncryptedJar(storage Storage, keys KeyProvider) (*Jar, error) {
+	return New(&Options{
+		PublicSuffixList: testPSL{},
+		Storage:          storage,
+		Encryption:       keys,
+	})
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	j0, err := newEncryptedJar(storage, StaticKey(testKey0))
+	c.Assert(err, qt.Equals, nil)
+	now := time.Now()
+	setCookies(j0, "http://www.host.test", []string{"a=secret-token; max-age=100"}, now)
+	c.Assert(j0.Save(), qt.Equals, nil)
+	c.Assert(bytes.HasPrefix(storage.data, []byte(encryptedMagic)), qt.Equals, true)
+	c.Assert(bytes.Contains(storage.data, []byte("secret-token")), qt.Equals, false)
+
+	j1, err := newEncryptedJar(storage, StaticKey(testKey0))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(allCookies(j1, now), qt.Equals, "a=secret-token")
+	c.Assert(j1.dirty, qt.Equals, false)
+
+	// Changes made by other jars are merged in as usual.
+	setCookies(j1, "http://www.host.test", []string{"b=b; max-age=100"}, now)
+	c.Assert(j1.Save(), qt.Equals, nil)
+	c.Assert(j0.Save(), qt.Equals, nil)
+	c.Assert(allCookies(j0, now), qt.Equals, "a=secret-token b=b")
+}
+
+func TestEncryptedStorageWrongKey(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	j0, err := newEncryptedJar(storage, StaticKey(testKey0))
+	c.Assert(err, qt.Equals, nil)
+	// A jar that loaded the storage before it held any cookies.
+	j1, err := newEncryptedJar(storage, StaticKey(testKey1))
+	c.Assert(err, qt.Equals, nil)
+	setCookies(j0, "http://www.host.test", []string{"a=a; max-age=100"}, time.Now())
+	c.Assert(j0.Save(), qt.Equals, nil)
+	stored := storage.data
+
+	_, err = newEncryptedJar(storage, StaticKey(testKey1))
+	c.Assert(err, qt.ErrorMatches, `cannot load cookies: cannot decrypt cookies \(wrong key\?\)`)
+	_, err = newEncryptedJar(storage, nil)
+	c.Assert(err, qt.ErrorMatches, `cannot load cookies: cookies are encrypted but no key was provided`)
+
+	setCookies(j1, "http://www.host.test", []string{"b=b; max-age=100"}, time.Now())
+	// Saving again must fail in the same way rather than
+	// treating the data it could not read as already merged.
+	for i := 0; i < 2; i++ {
+		err = j1.Save()
+		c.Assert(err, qt.ErrorMatches, `cannot decrypt cookies \(wrong key\?\)`)
+		c.Assert(storage.data, qt.DeepEquals, stored)
+	}
+}
+
+func TestEncryptedStorageWrongKeyReload(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	j0, err := newEncryptedJar(storage, StaticKey(testKey0))
+	c.Assert(err, qt.Equals, nil)
+	j1, err := newEncryptedJar(storage, StaticKey(testKey1))
+	c.Assert(err, qt.Equals, nil)
+	setCookies(j0, "http://www.host.test", []string{"a=a; max-age=100"}, time.Now())
+	c.Assert(j0.Save(), qt.Equals, nil)
+	stored := storage.data
+
+	// As when watching the storage for changes.
+	err = j1.reload()
+	c.Assert(err, qt.ErrorMatches, `cannot decrypt cookies \(wrong key\?\)`)
+
+	setCookies(j1, "http://www.host.test", []string{"b=b; max-age=100"}, time.Now())
+	err = j1.Save()
+	c.Assert(err, qt.ErrorMatches, `cannot decrypt cookies \(wrong key\?\)`)
+	c.Assert(storage.data, qt.DeepEquals, stored)
+}
+
+func TestEncryptedStorageTampered(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	j0, err := newEncryptedJar(storage, StaticKey(testKey0))
+	c.Assert(err, qt.Equals, nil)
+	setCookies(j0, "http://www.host.test", []string{"a=a; max-age=100"}, time.Now())
+	c.Assert(j0.Save(), qt.Equals, nil)
+
+	for _, i := range []int{len(encryptedMagic), len(storage.data) - 1} {
+		data := append([]byte(nil), storage.data...)
+		data[i] ^= 1
+		_, err = newEncryptedJar(&memStorage{data: data}, StaticKey(testKey0))
+		c.Assert(err, qt.ErrorMatches, `cannot load cookies: cannot decrypt cookies \(wrong key\?\)`)
+	}
+	_, err = newEncryptedJar(&memStorage{data: storage.data[:len(encryptedMagic)+1]}, StaticKey(testKey0))
+	c.Assert(err, qt.ErrorMatches, `cannot load cookies: encrypted cookies are truncated`)
+}
+
+func TestEncryptedStorageUpgrade(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	j0 := newStorageJar(c, storage)
+	now := time.Now()
+	setCookies(j0, "http://www.host.test", []string{"a=a; max-age=100"}, now)
+	c.Assert(j0.Save(), qt.Equals, nil)
+	c.Assert(bytes.HasPrefix(storage.data, []byte("[")), qt.Equals, true)
+
+	j1, err := newEncryptedJar(storage, StaticKey(testKey0))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(allCookies(j1, now), qt.Equals, "a=a")
+	c.Assert(j1.dirty, qt.Equals, true)
+	c.Assert(j1.Save(), qt.Equals, nil)
+	c.Assert(bytes.HasPrefix(storage.data, []byte(encryptedMagic)), qt.Equals, true)
+
+	j2, err := newEncryptedJar(storage, StaticKey(testKey0))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(allCookies(j2, now), qt.Equals, "a=a")
+}
+
+func TestEncryptedStorageKeyCalls(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	var salts [][]byte
+	keys := KeyFunc(func(salt []byte) ([]byte, error) {
+		salts = append(salts, append([]byte(nil), salt...))
+		return testKey0, nil
+	})
+	j0, err := newEncryptedJar(storage, keys)
+	c.Assert(err, qt.Equals, nil)
+	for i := 0; i < 3; i++ {
+		setCookies(j0, "http://www.host.test", []string{"a=a; max-age=100"}, time.Now())
+		c.Assert(j0.Save(), qt.Equals, nil)
+	}
+	j1, err := newEncryptedJar(storage, keys)
+	c.Assert(err, qt.Equals, nil)
+	setCookies(j1, "http://www.host.test", []string{"b=b; max-age=100"}, time.Now())
+	c.Assert(j1.Save(), qt.Equals, nil)
+
+	// The salt is chosen once and then kept, so each
+	// jar needs the key only once.
+	c.Assert(len(salts), qt.Equals, 2)
+	c.Assert(len(salts[0]), qt.Equals, encryptedSaltSize)
+	c.Assert(salts[1], qt.DeepEquals, salts[0])
+	c.Assert(storage.data[len(encryptedMagic):len(encryptedMagic)+encryptedSaltSize], qt.DeepEquals, salts[0])
+}
+
+func TestEncryptedStorageBadKey(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	j0, err := newEncryptedJar(storage, StaticKey([]byte("short")))
+	c.Assert(err, qt.Equals, nil)
+	setCookies(j0, "http://www.host.test", []string{"a=a; max-age=100"}, time.Now())
+	err = j0.Save()
+	c.Assert(err, qt.ErrorMatches, `cannot encrypt cookies: encryption key is 5 bytes long; need 32`)
+	c.Assert(storage.stores, qt.Equals, 0)
+}
+
+func TestPassphraseFile(t *testing.T) {
+	c := qt.New(t)
+	defer patchPassphraseIterations(10)()
+	d, err := ioutil.TempDir("", "")
+	c.Assert(err, qt.Equals, nil)
+	defer os.RemoveAll(d)
+	passphraseFile := filepath.Join(d, "passphrase")
+	err = ioutil.WriteFile(passphraseFile, []byte("correct horse battery staple\n"), 0600)
+	c.Assert(err, qt.Equals, nil)
+
+	salt := []byte("0123456789abcdef")
+	key, err := PassphraseFile(passphraseFile).Key(salt)
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(key, qt.DeepEquals, pbkdf2Key(sha256.New, []byte("correct horse battery staple"), salt, 10, 32))
+
+	cookieFile := filepath.Join(d, "cookies")
+	j0, err := New(&Options{
+		PublicSuffixList: testPSL{},
+		Filename:         cookieFile,
+		Encryption:       PassphraseFile(passphraseFile),
+	})
+	c.Assert(err, qt.Equals, nil)
+	now := time.Now()
+	setCookies(j0, "http://www.host.test", []string{"a=a; max-age=100"}, now)
+	c.Assert(j0.Save(), qt.Equals, nil)
+
+	j1, err := New(&Options{
+		PublicSuffixList: testPSL{},
+		Filename:         cookieFile,
+		Encryption:       PassphraseFile(passphraseFile),
+	})
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(allCookies(j1, now), qt.Equals, "a=a")
+
+	err = ioutil.WriteFile(passphraseFile, []byte("\n"), 0600)
+	c.Assert(err, qt.Equals, nil)
+	_, err = New(&Options{
+		PublicSuffixList: testPSL{},
+		Filename:         cookieFile,
+		Encryption:       PassphraseFile(passphraseFile),
+	})
+	c.Assert(err, qt.ErrorMatches, `cannot load cookies: cannot get encryption key: passphrase file ".*" is empty`)
+}
+
+func patchPassphraseIterations(n int) func() {
+	old := passphraseIterations
+	passphraseIterations = n
+	return func() {
+		passphraseIterations = old
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/errgo.v1"
+)
+
+// Entry holds everything that a jar records about a cookie, including
+// the state defined by RFC 6265 section 5.3 that http.Cookie cannot
+// represent. It can be used to copy, back up or migrate the contents
+// of a jar without loss. See Jar.Entries and Jar.SetEntries.
+type Entry struct {
+	Name  string
+	Value string
+
+	// Domain holds the domain that the cookie is
+	// sent to, without a leading dot.
+	Domain string
+	Path   string
+	Secure bool
+
+	HttpOnly bool
+
+	// Persistent records whether the cookie had an expiry
+	// time. Session cookies have Persistent set to false.
+	Persistent bool
+
+	// HostOnly records whether the cookie is sent only to the
+	// host named by Domain rather than also to its subdomains.
+	HostOnly bool
+
+	// Expires holds the time that the cookie expires. Removed
+	// cookies are kept for a while as tombstones with an Expires
+	// time in the past, so that the removal can be merged into
+	// other jars.
+	Expires    time.Time
+	Creation   time.Time
+	LastAccess time.Time
+
+	// Updated records when the cookie was updated.
+	// This is different from creation time because a cookie
+	// can be changed without updating the creation time.
+	Updated time.Time
+
+	// CanonicalHost holds the canonical host name that the
+	// cookie was received from. It must not be empty.
+	CanonicalHost string
+
+	// PartitionKey holds the top-level site (for example
+	// "https://example.com") that a partitioned cookie was set
+	// under. It is empty for cookies that are not partitioned.
+	PartitionKey string `json:",omitempty"`
+
+	// SameSite holds the SameSite attribute of the cookie:
+	// one of "Strict", "Lax" or "None", or empty if the
+	// attribute was not specified.
+	SameSite string `json:",omitempty"`
+}
+
+// Entries returns all the entries in the jar, including session
+// cookies, sorted by canonical host name and then as for Cookies.
+// Expired and removed cookies are included only if includeTombstones
+// is true. This function does not modify the cookie jar.
+func (j *Jar) Entries(includeTombstones bool) []Entry {
+	return j.allEntries(includeTombstones, time.Now())
+}
+
+// allEntries is like Entries but takes the current time as a parameter.
+func (j *Jar) allEntries(includeTombstones bool, now time.Time) []Entry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return allEntries(j.entries, includeTombstones, now)
+}
+
+// allEntries returns the given entries as described by Jar.Entries.
+func allEntries(entries map[string]map[string]entry, includeTombstones bool, now time.Time) []Entry {
+	var selected []entry
+	for _, submap := range entries {
+		for _, e := range submap {
+			if includeTombstones || e.Expires.After(now) {
+				selected = append(selected, e)
+			}
+		}
+	}
+	sort.Sort(byCanonicalHost{byPathLength(selected)})
+	result := make([]Entry, len(selected))
+	for i, e := range selected {
+		result[i] = Entry(e)
+	}
+	return result
+}
+
+// setImportedDomain sets the Domain, HostOnly and CanonicalHost fields
+// of e for a cookie imported from another program. The domain may
+// have a leading dot, and hostOnly holds whether the cookie is sent
+// only to that host. As the host that set the cookie is not known,
+// the domain is used as the canonical host.
+//
+// It returns a *RejectError if the jar would not accept such a cookie
+// from a server.
+func (j *Jar) setImportedDomain(e *entry, domain string, hostOnly bool) error {
+	host, err := canonicalHost(strings.TrimPrefix(domain, "."))
+	if err != nil || host == "" {
+		return errgo.Newf("invalid domain %q", domain)
+	}
+	e.CanonicalHost = host
+	if hostOnly {
+		e.Domain, e.HostOnly = host, true
+		return nil
+	}
+	e.Domain, e.HostOnly, err = j.domainAndType(host, host)
+	return err
+}
+
+// SetEntries stores the given entries in the jar exactly as they are,
+// replacing any existing entries for the same cookies regardless of
+// when they were updated. It is the inverse of Entries.
+//
+// It returns an error without changing the jar if any of the entries
+// has an empty CanonicalHost or Domain, or a Path that does not start
+// with "/".
+func (j *Jar) SetEntries(entries []Entry) error {
+	return j.setEntries(entries, time.Now())
+}
+
+// setEntries is like SetEntries but takes the current time as a
+// parameter.
+func (j *Jar) setEntries(entries []Entry, now time.Time) error {
+	for _, e := range entries {
+		switch {
+		case e.CanonicalHost == "":
+			return errgo.Newf("cookie %q has no canonical host", e.Name)
+		case e.Domain == "":
+			return errgo.Newf("cookie %q has no domain", e.Name)
+		case !strings.HasPrefix(e.Path, "/"):
+			return errgo.Newf("cookie %q has invalid path %q", e.Name, e.Path)
+		}
+	}
+	converted := make([]entry, len(entries))
+	for i, e := range entries {
+		converted[i] = entry(e)
+	}
+	defer j.notify()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.replaceEntries(converted, now)
+	j.evict(now)
+	return nil
+}
+
+// replaceEntries stores the given entries in the jar, replacing any
+// existing entries for the same cookies regardless of when they were
+// updated. It must be called with j.mu held.
+func (j *Jar) replaceEntries(entries []entry, now time.Time) {
+	for _, e := range entries {
+		key := jarKey(e.CanonicalHost, j.psList)
+		id := e.id()
+		submap := j.entries[key]
+		if submap == nil {
+			submap = make(map[string]entry)
+			j.entries[key] = submap
+		}
+		old, ok := submap[id]
+		oldLive := ok && old.Expires.After(now)
+		switch {
+		case !e.Expires.After(now):
+			if oldLive {
+				j.event(CookieDeleted, &old, nil)
+			}
+		case oldLive:
+			j.event(CookieUpdated, &e, nil)
+		default:
+			j.event(CookieAdded, &e, nil)
+		}
+		submap[id] = e
+		j.changed()
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"net/http"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestEntriesRoundTrip(t *testing.T) {
+	c := qt.New(t)
+	j0 := newTestJar("")
+	setCookies(j0, "https://www.host.test/foo/", []string{
+		"session=1",
+		"persistent=1; max-age=100; domain=host.test; path=/",
+		"secure=1; secure; httponly; samesite=strict",
+		"removed=1",
+	}, tNow)
+	j0.setCookiesForRequest(mustParseURL("https://embed.test/"), mustParseURL("https://site.test/"), []*http.Cookie{{Name: "partitioned", Value: "1", Secure: true, Partitioned: true}}, tNow)
+	setCookies(j0, "https://www.host.test/foo/", []string{"removed=; max-age=-1"}, atTime(1))
+	queryJar(j0, "https://www.host.test/foo/", atTime(2))
+
+	entries := j0.allEntries(true, atTime(3))
+	c.Assert(len(entries), qt.Equals, 5)
+	live := j0.allEntries(false, atTime(3))
+	var names []string
+	for _, e := range live {
+		names = append(names, e.Name)
+	}
+	c.Assert(names, qt.DeepEquals, []string{"partitioned", "secure", "session", "persistent"})
+
+	j1 := newTestJar("")
+	err := j1.setEntries(entries, atTime(3))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(j1.allEntries(true, atTime(3)), qt.DeepEquals, entries)
+	c.Assert(j1.entries, qt.DeepEquals, j0.entries)
+}
+
+func TestSetEntriesOverwrites(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	setCookies(jar, "http://www.host.test/", []string{"a=new"}, atTime(10))
+	entries := jar.allEntries(false, atTime(10))
+	c.Assert(len(entries), qt.Equals, 1)
+
+	// An older entry replaces a newer one.
+	entries[0].Value = "old"
+	entries[0].Updated = atTime(0)
+	err := jar.setEntries(entries, atTime(10))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(allCookies(jar, atTime(10)), qt.Equals, "a=old")
+	c.Assert(jar.allEntries(false, atTime(10)), qt.DeepEquals, entries)
+}
+
+func TestSetEntriesNoCanonicalHost(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	err := jar.setEntries([]Entry{{
+		Name:          "a",
+		Value:         "1",
+		Domain:        "www.host.test",
+		Path:          "/",
+		Expires:       endOfTime,
+		CanonicalHost: "www.host.test",
+	}, {
+		Name:    "b",
+		Value:   "1",
+		Domain:  "www.host.test",
+		Path:    "/",
+		Expires: endOfTime,
+	}}, tNow)
+	c.Assert(err, qt.ErrorMatches, `cookie "b" has no canonical host`)
+	c.Assert(allCookies(jar, tNow), qt.Equals, "")
+}
+
+var setEntriesInvalidTests = []struct {
+	about  string
+	entry  Entry
+	expect string
+}{{
+	about: "no domain",
+	entry: Entry{
+		Name:          "a",
+		Path:          "/",
+		CanonicalHost: "www.host.test",
+	},
+	expect: `cookie "a" has no domain`,
+}, {
+	about: "empty path",
+	entry: Entry{
+		Name:          "a",
+		Domain:        "www.host.test",
+		CanonicalHost: "www.host.test",
+	},
+	expect: `cookie "a" has invalid path ""`,
+}, {
+	about: "relative path",
+	entry: Entry{
+		Name:          "a",
+		Domain:        "www.host.test",
+		Path:          "foo/",
+		CanonicalHost: "www.host.test",
+	},
+	expect: `cookie "a" has invalid path "foo/"`,
+}}
+
+func TestSetEntriesInvalid(t *testing.T) {
+	for _, test := range setEntriesInvalidTests {
+		jar := newTestJar("")
+		e := test.entry
+		e.Expires = endOfTime
+		err := jar.setEntries([]Entry{e}, tNow)
+		if err == nil || err.Error() != test.expect {
+			t.Errorf("%s: got error %v want %q", test.about, err, test.expect)
+		}
+		// Querying the jar must not panic.
+		if got := queryJar(jar, "http://www.host.test/", tNow); got != "" {
+			t.Errorf("%s: jar unexpectedly holds cookies %q", test.about, got)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// EventKind identifies the kind of change described by an Event.
+type EventKind int
+
+const (
+	// CookieAdded is the kind of event sent when a cookie
+	// is set that was not previously in the jar.
+	CookieAdded EventKind = iota + 1
+
+	// CookieUpdated is the kind of event sent when a cookie
+	// is set that replaces one with the same name, domain
+	// and path.
+	CookieUpdated
+
+	// CookieDeleted is the kind of event sent when a cookie
+	// is removed from the jar, either explicitly or because
+	// a server set it again with an expiry time in the past,
+	// or because it was evicted.
+	CookieDeleted
+
+	// CookieExpired is the kind of event sent when a cookie is
+	// found to have passed its expiry time. This happens when
+	// the jar is queried for the cookie's URL.
+	CookieExpired
+
+	// CookieMerged is the kind of event sent when a cookie is
+	// added or changed by merging in cookies from storage.
+	// Cookies that were removed in storage are also reported
+	// this way, with an expiry time in the past.
+	CookieMerged
+)
+
+var eventKindNames = map[EventKind]string{
+	CookieAdded:   "added",
+	CookieUpdated: "updated",
+	CookieDeleted: "deleted",
+	CookieExpired: "expired",
+	CookieMerged:  "merged",
+}
+
+// String implements fmt.Stringer.
+func (k EventKind) String() string {
+	if name, ok := eventKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// Event describes a change to a cookie in a jar.
+type Event struct {
+	// Kind holds the kind of change.
+	Kind EventKind
+
+	// Cookie holds the cookie with all its attributes. For
+	// CookieDeleted and CookieExpired events, it holds the cookie
+	// as it was before it was deleted or expired. The Domain field
+	// is always set, even for host-only cookies.
+	Cookie *http.Cookie
+
+	// URL holds the URL that the cookie was set or queried
+	// with, or nil if the change was not caused by a request,
+	// for example because it was removed with RemoveAll or
+	// merged from storage.
+	URL *url.URL
+}
+
+// subscriber holds a function registered with Subscribe.
+type subscriber struct {
+	id int
+	f  func(Event)
+}
+
+// Subscribe arranges for f to be called for each change made to the
+// cookies in the jar, and returns a function that cancels the
+// subscription.
+//
+// Events are delivered in order after the change has been made. Calls
+// to f are never concurrent, but may happen on any goroutine that uses
+// the jar. The function may itself use the jar; events caused by
+// doing so are delivered after f returns.
+func (j *Jar) Subscribe(f func(Event)) (cancel func()) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	id := j.nextSubscriberID
+	j.nextSubscriberID++
+	j.subscribers = append(j.subscribers, subscriber{
+		id: id,
+		f:  f,
+	})
+	return func() {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		for i, s := range j.subscribers {
+			if s.id == id {
+				j.subscribers = append(j.subscribers[:i:i], j.subscribers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// event records a change to the given entry so that it will be
+// delivered by notify. It must be called with j.mu held.
+func (j *Jar) event(kind EventKind, e *entry, u *url.URL) {
+	if len(j.subscribers) == 0 {
+		return
+	}
+	j.pending = append(j.pending, Event{
+		Kind:   kind,
+		Cookie: e.cookie(),
+		URL:    u,
+	})
+}
+
+// notify delivers any pending events to the subscribers. It must
+// be called without j.mu held.
+func (j *Jar) notify() {
+	j.mu.Lock()
+	if j.notifying {
+		// Another call to notify is delivering events
+		// and will deliver ours too.
+		j.mu.Unlock()
+		return
+	}
+	j.notifying = true
+	for {
+		events, subscribers := j.pending, j.subscribers
+		if len(events) == 0 {
+			j.notifying = false
+			j.mu.Unlock()
+			return
+		}
+		j.pending = nil
+		j.mu.Unlock()
+		for _, ev := range events {
+			for _, s := range subscribers {
+				s.f(ev)
+			}
+		}
+		j.mu.Lock()
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// eventRecorder records events from a jar in
+// the form "kind name=value url".
+type eventRecorder struct {
+	events []string
+}
+
+func (r *eventRecorder) record(ev Event) {
+	u := "-"
+	if ev.URL != nil {
+		u = ev.URL.String()
+	}
+	r.events = append(r.events, fmt.Sprintf("%v %s=%s %s", ev.Kind, ev.Cookie.Name, ev.Cookie.Value, u))
+}
+
+func (r *eventRecorder) take() string {
+	s := strings.Join(r.events, "; ")
+	r.events = nil
+	return s
+}
+
+func TestEvents(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	var r eventRecorder
+	cancel := jar.Subscribe(r.record)
+
+	setCookies(jar, "http://www.host.test/", []string{"a=1", "b=2; max-age=10"}, atTime(0))
+	c.Assert(r.take(), qt.Equals, "added a=1 http://www.host.test/; added b=2 http://www.host.test/")
+
+	setCookies(jar, "http://www.host.test/", []string{"a=3", "c=; max-age=-1"}, atTime(1))
+	c.Assert(r.take(), qt.Equals, "updated a=3 http://www.host.test/")
+
+	setCookies(jar, "http://www.host.test/", []string{"a=; max-age=-1"}, atTime(2))
+	c.Assert(r.take(), qt.Equals, "deleted a=3 http://www.host.test/")
+
+	queryJar(jar, "http://www.host.test/", atTime(20))
+	c.Assert(r.take(), qt.Equals, "expired b=2 http://www.host.test/")
+
+	setCookies(jar, "http://other.test/", []string{"d=4"}, atTime(21))
+	r.take()
+	jar.RemoveAll()
+	c.Assert(r.take(), qt.Equals, "deleted d=4 -")
+
+	cancel()
+	setCookies(jar, "http://other.test/", []string{"e=5"}, atTime(22))
+	c.Assert(r.take(), qt.Equals, "")
+}
+
+func TestEventAttributes(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	var events []Event
+	jar.Subscribe(func(ev Event) {
+		events = append(events, ev)
+	})
+	setCookies(jar, "https://www.host.test/foo/bar", []string{
+		"a=1; domain=host.test; secure; httponly; samesite=strict; " + expiresIn(10),
+	}, tNow)
+	c.Assert(len(events), qt.Equals, 1)
+	c.Assert(cookiesEqual(events[0].Cookie, &http.Cookie{
+		Name:     "a",
+		Value:    "1",
+		Domain:   "host.test",
+		Path:     "/foo",
+		Expires:  atTime(10).Truncate(time.Second),
+		Secure:   true,
+		HttpOnly: true,
+	}), qt.Equals, true)
+	c.Assert(events[0].Cookie.SameSite, qt.Equals, http.SameSiteStrictMode)
+}
+
+func TestEventsMerged(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	j0 := newStorageJar(c, storage)
+	var r eventRecorder
+	j0.Subscribe(r.record)
+
+	j1 := newStorageJar(c, storage)
+	setCookies(j1, "http://www.host.test/", []string{"a=1; max-age=100"}, time.Now())
+	c.Assert(j1.Save(), qt.Equals, nil)
+
+	c.Assert(j0.Save(), qt.Equals, nil)
+	c.Assert(r.take(), qt.Equals, "merged a=1 -")
+}
+
+func TestEventsReentrant(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	var r eventRecorder
+	jar.Subscribe(func(ev Event) {
+		r.record(ev)
+		if ev.Cookie.Name == "a" {
+			setCookies(jar, "http://www.host.test/", []string{"b=2"}, tNow)
+		}
+	})
+	setCookies(jar, "http://www.host.test/", []string{"a=1"}, tNow)
+	c.Assert(r.take(), qt.Equals, "added a=1 http://www.host.test/; added b=2 http://www.host.test/")
+}
@@ -0,0 +1,152 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Check identifies a check that prevents a stored cookie from being
+// sent with a request.
+type Check int
+
+const (
+	// CheckExpired fails when the cookie has expired
+	// or has been removed.
+	CheckExpired Check = iota + 1
+
+	// CheckHostOnly fails when the cookie is a host-only
+	// cookie and the request's host is not the host
+	// that set it.
+	CheckHostOnly
+
+	// CheckDomain fails when the request's host does not
+	// domain-match the cookie's domain.
+	CheckDomain
+
+	// CheckPath fails when the request's path does not
+	// path-match the cookie's path.
+	CheckPath
+
+	// CheckSecure fails when the cookie has the Secure
+	// attribute and the request does not use https.
+	CheckSecure
+
+	// CheckPartition fails when the cookie is partitioned
+	// and was set from within a different top-level site.
+	CheckPartition
+)
+
+var checkNames = map[Check]string{
+	CheckExpired:   "expired",
+	CheckHostOnly:  "host-only cookie for another host",
+	CheckDomain:    "domain mismatch",
+	CheckPath:      "path mismatch",
+	CheckSecure:    "secure cookie over insecure connection",
+	CheckPartition: "partitioned for another site",
+}
+
+// String implements fmt.Stringer.
+func (c Check) String() string {
+	if name, ok := checkNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Check(%d)", int(c))
+}
+
+// Explanation describes whether a stored cookie
+// would be sent with a request.
+type Explanation struct {
+	// Cookie holds the stored cookie with all its attributes.
+	// Its Value is empty if it has expired.
+	Cookie *http.Cookie
+
+	// Sent holds whether the cookie would be sent.
+	Sent bool
+
+	// Failed holds the first check that prevents the
+	// cookie from being sent, or zero if Sent is true.
+	Failed Check
+}
+
+// Explain reports, for every cookie stored under the registrable
+// domain (eTLD+1) of u, whether the cookie would be returned by
+// Cookies(u) and, if not, why not. If name is non-empty, only cookies
+// with that name are reported. Removed and expired cookies that are
+// still held by the jar are included.
+//
+// The explanations are in the same order that Cookies returns
+// cookies. It returns nil if the URL's scheme is not HTTP or HTTPS.
+// Explain does not modify the jar.
+func (j *Jar) Explain(u *url.URL, name string) []Explanation {
+	return j.explain(u, name, time.Now())
+}
+
+// explain is like Explain but takes the current time as a parameter.
+func (j *Jar) explain(u *url.URL, name string, now time.Time) []Explanation {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return nil
+	}
+	key := jarKey(host, j.psList)
+	site := j.siteOf(u)
+	https := u.Scheme == "https"
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var selected []entry
+	for _, e := range j.entries[key] {
+		if name == "" || e.Name == name {
+			selected = append(selected, e)
+		}
+	}
+	sort.Sort(byPathLength(selected))
+	explanations := make([]Explanation, len(selected))
+	for i := range selected {
+		e := &selected[i]
+		failed := e.failedCheck(https, host, path, site, now)
+		explanations[i] = Explanation{
+			Cookie: e.cookie(),
+			Sent:   failed == 0,
+			Failed: failed,
+		}
+	}
+	return explanations
+}
+
+// failedCheck returns the first check that prevents e's cookie from
+// being included in a request to host/path made from within the given
+// top-level site at the given time, or zero if there is none. It
+// makes the same decision as shouldSend together with the expiry check
+// in Jar.cookies.
+func (e *entry) failedCheck(https bool, host, path, site string, now time.Time) Check {
+	switch {
+	case !e.Expires.After(now):
+		return CheckExpired
+	case !e.domainMatch(host) && e.HostOnly:
+		return CheckHostOnly
+	case !e.domainMatch(host):
+		return CheckDomain
+	case !e.pathMatch(path):
+		return CheckPath
+	case !https && e.Secure:
+		return CheckSecure
+	case !e.partitionMatch(site):
+		return CheckPartition
+	}
+	return 0
+}
@@ -0,0 +1,87 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+var explainTests = []struct {
+	toURL string
+	name  string
+	want  string
+}{{
+	toURL: "http://www.host.test/foo/bar",
+	want:  "path=sent domain=sent expired=expired host=sent other=host-only cookie for another host secure=secure cookie over insecure connection",
+}, {
+	toURL: "https://www.host.test/",
+	want:  "path=path mismatch domain=sent expired=expired host=sent other=host-only cookie for another host secure=sent",
+}, {
+	toURL: "https://sub.host.test/foo",
+	want:  "path=host-only cookie for another host domain=domain mismatch expired=expired host=host-only cookie for another host other=sent secure=host-only cookie for another host",
+}, {
+	toURL: "https://host.test/",
+	want:  "path=host-only cookie for another host domain=domain mismatch expired=expired host=host-only cookie for another host other=host-only cookie for another host secure=host-only cookie for another host",
+}, {
+	toURL: "https://www.host.test/",
+	name:  "secure",
+	want:  "secure=sent",
+}, {
+	toURL: "https://www.host.test/",
+	name:  "missing",
+}, {
+	toURL: "ftp://www.host.test/",
+}}
+
+func TestExplain(t *testing.T) {
+	jar := newTestJar("")
+	setCookies(jar, "http://www.host.test/", []string{
+		"domain=1; domain=www.host.test",
+		"expired=1; max-age=1",
+		"host=1",
+		"path=1; path=/foo/bar",
+		"secure=1; secure",
+	}, tNow)
+	setCookies(jar, "http://sub.host.test/", []string{"other=1"}, tNow)
+	now := tNow.Add(2 * time.Second)
+	for _, test := range explainTests {
+		var got []string
+		for _, e := range jar.explain(mustParseURL(test.toURL), test.name, now) {
+			if e.Sent != (e.Failed == 0) {
+				t.Errorf("%s: inconsistent explanation %#v", test.toURL, e)
+			}
+			reason := "sent"
+			if !e.Sent {
+				reason = e.Failed.String()
+			}
+			got = append(got, fmt.Sprintf("%s=%s", e.Cookie.Name, reason))
+		}
+		if got := strings.Join(got, " "); got != test.want {
+			t.Errorf("%s %q\ngot  %q\nwant %q", test.toURL, test.name, got, test.want)
+		}
+	}
+}
+
+func TestExplainMatchesCookies(t *testing.T) {
+	for _, test := range basicsTests {
+		jar := newTestJar("")
+		setCookies(jar, test.fromURL, test.setCookies, tNow)
+		now := tNow.Add(1001 * time.Millisecond)
+		for _, query := range test.queries {
+			var sent []string
+			for _, e := range jar.explain(mustParseURL(query.toURL), "", now) {
+				if e.Sent {
+					sent = append(sent, e.Cookie.Name+"="+e.Cookie.Value)
+				}
+			}
+			if got := strings.Join(sent, " "); got != query.want {
+				t.Errorf("Test %q %s\ngot  %q\nwant %q", test.description, query.toURL, got, query.want)
+			}
+		}
+	}
+}
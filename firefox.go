@@ -0,0 +1,202 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/errgo.v1"
+)
+
+// ImportFirefox merges the cookies from the moz_cookies table of the
+// given Firefox cookies.sqlite file into the jar. The file is only
+// ever read, so it may be imported from the profile of a running
+// browser; committed changes that are still in the database's
+// write-ahead log are included.
+//
+// Only cookies from the default container are imported; cookies from
+// private windows and other containers are ignored. As for cookies
+// merged from storage, a cookie replaces one already in the jar only
+// if it was last used in Firefox more recently than the jar's cookie
+// was updated.
+func (j *Jar) ImportFirefox(filename string) error {
+	return j.importFirefox(filename, time.Now())
+}
+
+// importFirefox is like ImportFirefox but takes the current time as a
+// parameter.
+func (j *Jar) importFirefox(filename string, now time.Time) error {
+	db, err := openSQLite(filename)
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	t, err := db.table("moz_cookies")
+	if err != nil {
+		return errgo.Notef(err, "cannot read Firefox cookies")
+	}
+	col := firefoxColumns(t)
+	for _, name := range []string{"name", "value", "host", "path", "expiry"} {
+		if col[name] < 0 {
+			return errgo.Newf("cannot read Firefox cookies: no %s column", name)
+		}
+	}
+	var entries []entry
+	for _, row := range t.rows {
+		e, ok := j.firefoxEntry(func(name string) interface{} {
+			if i := col[name]; i >= 0 {
+				return row[i]
+			}
+			return nil
+		}, now)
+		if ok {
+			entries = append(entries, e)
+		}
+	}
+	defer j.notify()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.merge(entries) {
+		j.changed()
+		j.evict(now)
+	}
+	return nil
+}
+
+// firefoxColumns returns the indexes of the columns of moz_cookies
+// that we use, with -1 for columns that are not present.
+func firefoxColumns(t *sqliteTable) map[string]int {
+	col := make(map[string]int)
+	for _, name := range []string{
+		"originAttributes",
+		"name",
+		"value",
+		"host",
+		"path",
+		"expiry",
+		"lastAccessed",
+		"creationTime",
+		"isSecure",
+		"isHttpOnly",
+		"sameSite",
+	} {
+		col[name] = t.column(name)
+	}
+	return col
+}
+
+// firefoxEntry returns the entry for the moz_cookies row with the
+// given values. It returns false if the cookie should be ignored.
+func (j *Jar) firefoxEntry(value func(column string) interface{}, now time.Time) (entry, bool) {
+	var e entry
+	partition, ok := firefoxPartitionKey(sqliteString(value("originAttributes")))
+	if !ok {
+		return e, false
+	}
+	e.PartitionKey = partition
+	host := sqliteString(value("host"))
+	if err := j.setImportedDomain(&e, host, !strings.HasPrefix(host, ".")); err != nil {
+		return e, false
+	}
+	e.Name = sqliteString(value("name"))
+	e.Value = sqliteString(value("value"))
+	e.Path = sqliteString(value("path"))
+	if e.Path == "" || e.Path[0] != '/' {
+		e.Path = "/"
+	}
+	e.Secure = sqliteInt64(value("isSecure")) != 0
+	e.HttpOnly = sqliteInt64(value("isHttpOnly")) != 0
+	switch sqliteInt64(value("sameSite")) {
+	case 1:
+		e.SameSite = "Lax"
+	case 2:
+		e.SameSite = "Strict"
+	}
+	// Firefox only stores persistent cookies. Recent versions
+	// store the expiry time in milliseconds rather than seconds.
+	e.Persistent = true
+	expiry := sqliteInt64(value("expiry"))
+	if expiry > 1e11 {
+		e.Expires = time.Unix(expiry/1e3, expiry%1e3*1e6).UTC()
+	} else {
+		e.Expires = time.Unix(expiry, 0).UTC()
+	}
+	if !e.Expires.After(now) {
+		return e, false
+	}
+	e.Creation = firefoxTime(value("creationTime"), now)
+	e.LastAccess = firefoxTime(value("lastAccessed"), e.Creation)
+	e.Updated = e.LastAccess
+	return e, true
+}
+
+// firefoxTime returns the time held in microseconds in the given
+// value, or def if there is none.
+func firefoxTime(v interface{}, def time.Time) time.Time {
+	t := sqliteInt64(v)
+	if t <= 0 {
+		return def
+	}
+	return time.Unix(t/1e6, t%1e6*1e3).UTC()
+}
+
+// firefoxPartitionKey returns the partition key held in the given
+// Firefox origin attributes, such as
+// "^partitionKey=%28https%2Cexample.com%29". It returns false if the
+// attributes are for a cookie outside the default container.
+func firefoxPartitionKey(attrs string) (string, bool) {
+	if attrs == "" {
+		return "", true
+	}
+	values, err := url.ParseQuery(strings.TrimPrefix(attrs, "^"))
+	if err != nil {
+		return "", false
+	}
+	for name, v := range values {
+		// Any other attribute (for example userContextId,
+		// privateBrowsingId or firstPartyDomain) means that
+		// the cookie is isolated from the default container.
+		if name != "partitionKey" && len(v) > 0 && v[0] != "" && v[0] != "0" {
+			return "", false
+		}
+	}
+	key := values.Get("partitionKey")
+	if key == "" {
+		return "", true
+	}
+	// The key has the form "(scheme,host)" or
+	// "(scheme,host,port)".
+	if !strings.HasPrefix(key, "(") || !strings.HasSuffix(key, ")") {
+		return "", false
+	}
+	parts := strings.Split(key[1:len(key)-1], ",")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+	return parts[0] + "://" + parts[1], true
+}
+
+// sqliteString returns v as a string.
+func sqliteString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+// sqliteInt64 returns v as an integer.
+func sqliteInt64(v interface{}) int64 {
+	switch v := v.(type) {
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	}
+	return 0
+}
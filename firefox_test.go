@@ -0,0 +1,123 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// The Firefox fixtures in testdata are generated by mkfirefox.py.
+
+var firefoxNow = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestImportFirefox(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	err := jar.importFirefox("testdata/firefox/cookies.sqlite", firefoxNow)
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(jar.dirty, qt.Equals, true)
+
+	var names []string
+	for _, e := range jar.allEntries(true, firefoxNow) {
+		if !strings.HasPrefix(e.CanonicalHost, "filler") {
+			names = append(names, e.Name)
+		}
+	}
+	c.Assert(names, qt.DeepEquals, []string{"partitioned", "domain", "long", "host"})
+	c.Assert(len(jar.allEntries(true, firefoxNow)), qt.Equals, 84)
+
+	created := time.Unix(1700000000, 0).UTC()
+	accessed := time.Unix(1730000000, 0).UTC()
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.Assert(jar.entries["example.com"], qt.DeepEquals, map[string]entry{
+		"www.example.com;/;host": {
+			Name:          "host",
+			Value:         "abc",
+			Domain:        "www.example.com",
+			Path:          "/",
+			Secure:        true,
+			HttpOnly:      true,
+			Persistent:    true,
+			HostOnly:      true,
+			Expires:       expires,
+			Creation:      created,
+			LastAccess:    accessed,
+			Updated:       accessed,
+			CanonicalHost: "www.example.com",
+			SameSite:      "Strict",
+		},
+		"example.com;/app;domain": {
+			Name:          "domain",
+			Value:         "d",
+			Domain:        "example.com",
+			Path:          "/app",
+			Persistent:    true,
+			Expires:       expires,
+			Creation:      created,
+			LastAccess:    accessed,
+			Updated:       accessed,
+			CanonicalHost: "example.com",
+			SameSite:      "Lax",
+		},
+	})
+	c.Assert(queryJar(jar, "https://sub.example.com/app/x", firefoxNow), qt.Equals, "domain=d")
+	cookies := jar.cookiesForRequest(mustParseURL("https://embed.test/"), mustParseURL("https://www.site.test/"), "", firefoxNow)
+	c.Assert(cookies, qt.DeepEquals, []*http.Cookie{{Name: "partitioned", Value: "pt"}})
+	c.Assert(queryJar(jar, "https://embed.test/", firefoxNow), qt.Equals, "")
+}
+
+func TestImportFirefoxNewestWins(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	setCookies(jar, "https://www.example.com/", []string{"host=newer"}, time.Unix(1740000000, 0))
+	setCookies(jar, "https://long.test/", []string{"long=older"}, time.Unix(1720000000, 0))
+	err := jar.importFirefox("testdata/firefox/cookies.sqlite", firefoxNow)
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(queryJar(jar, "https://www.example.com/", firefoxNow), qt.Equals, "host=newer")
+	c.Assert(queryJar(jar, "https://long.test/", firefoxNow), qt.Equals, "long="+strings.Repeat("x", 3000))
+}
+
+func TestImportFirefoxWAL(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	err := jar.importFirefox("testdata/firefox-wal/cookies.sqlite", firefoxNow)
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(queryJar(jar, "http://www.example.com/", firefoxNow), qt.Equals, "a=2 b=3")
+}
+
+func TestImportFirefoxNotFirefox(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	err := jar.importFirefox("testdata/firefox/nonexistent.sqlite", firefoxNow)
+	c.Assert(err, qt.ErrorMatches, "open testdata/firefox/nonexistent.sqlite: no such file or directory")
+}
+
+var firefoxPartitionKeyTests = []struct {
+	attrs string
+	want  string
+	ok    bool
+}{
+	{"", "", true},
+	{"^partitionKey=%28https%2Cexample.com%29", "https://example.com", true},
+	{"^partitionKey=%28https%2Cexample.com%2C8443%29", "https://example.com", true},
+	{"^userContextId=0", "", true},
+	{"^userContextId=1", "", false},
+	{"^firstPartyDomain=example.com", "", false},
+	{"^partitionKey=bogus", "", false},
+}
+
+func TestFirefoxPartitionKey(t *testing.T) {
+	for _, test := range firefoxPartitionKeyTests {
+		got, ok := firefoxPartitionKey(test.attrs)
+		if got != test.want || ok != test.ok {
+			t.Errorf("firefoxPartitionKey(%q) = %q, %v; want %q, %v", test.attrs, got, ok, test.want, test.ok)
+		}
+	}
+}
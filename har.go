@@ -0,0 +1,197 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/errgo.v1"
+)
+
+// harCookiesField holds the name of the field that AnnotateHAR adds
+// to each request in a HAR. Custom fields must start with an
+// underscore.
+const harCookiesField = "_jarCookies"
+
+// harFile holds the parts of an HTTP Archive (HAR) that ReadHAR uses.
+// See http://www.softwareishard.com/blog/har-12-spec/.
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+// harEntry holds the parts of a HAR entry that ReadHAR uses.
+type harEntry struct {
+	StartedDateTime string `json:"startedDateTime"`
+	Request         struct {
+		URL string `json:"url"`
+	} `json:"request"`
+	Response struct {
+		Headers []harNameValue `json:"headers"`
+	} `json:"response"`
+}
+
+// harNameValue holds a header or cookie in a HAR.
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ReadHAR reads an HTTP Archive (HAR), as saved by browser developer
+// tools and many proxies, from r and replays the Set-Cookie headers of
+// each response through the jar as if they had been passed to
+// SetCookies at the time that the request was started. The entries
+// are replayed in the order that they were started, regardless of
+// their order in the archive.
+//
+// The jar's rules and policy apply to the replayed cookies exactly as
+// they do to cookies received from a server. Cookies that have expired
+// since the archive was recorded are not returned by Cookies.
+func (j *Jar) ReadHAR(r io.Reader) error {
+	var har harFile
+	if err := json.NewDecoder(r).Decode(&har); err != nil {
+		return errgo.Notef(err, "cannot parse HAR")
+	}
+	type replay struct {
+		t       time.Time
+		u       *url.URL
+		cookies []*http.Cookie
+	}
+	replays := make([]replay, 0, len(har.Log.Entries))
+	for i, e := range har.Log.Entries {
+		t, err := time.Parse(time.RFC3339Nano, e.StartedDateTime)
+		if err != nil {
+			return errgo.Newf("entry %d: invalid startedDateTime %q", i, e.StartedDateTime)
+		}
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return errgo.Notef(err, "entry %d", i)
+		}
+		replays = append(replays, replay{
+			t:       t,
+			u:       u,
+			cookies: harSetCookies(e.Response.Headers),
+		})
+	}
+	sort.SliceStable(replays, func(i, k int) bool {
+		return replays[i].t.Before(replays[k].t)
+	})
+	for _, rp := range replays {
+		j.setCookies(rp.u, rp.cookies, rp.t)
+	}
+	return nil
+}
+
+// harSetCookies returns the cookies in the Set-Cookie headers in the
+// given HAR response headers.
+func harSetCookies(headers []harNameValue) []*http.Cookie {
+	h := make(http.Header)
+	for _, hdr := range headers {
+		if !strings.EqualFold(hdr.Name, "Set-Cookie") {
+			continue
+		}
+		// Some browsers record all the Set-Cookie headers of
+		// an HTTP/2 response as a single header with the values
+		// separated by newlines.
+		for _, v := range strings.Split(hdr.Value, "\n") {
+			if v = strings.TrimSpace(v); v != "" {
+				h.Add("Set-Cookie", v)
+			}
+		}
+	}
+	return (&http.Response{Header: h}).Cookies()
+}
+
+// AnnotateHAR reads an HTTP Archive (HAR) from r and writes it to w
+// with each request annotated with the cookies that Cookies would
+// return for the request's URL at the time that the request was
+// started, without changing the jar. Entries with no startedDateTime
+// field are annotated with the cookies that Cookies would return now.
+// The cookies are added as an array of name and value objects in the same format
+// as the request's cookies field, in a custom "_jarCookies" field, so
+// that they can be compared with the cookies that were actually sent.
+//
+// All the other fields in the archive are preserved, although they
+// may be reordered.
+func (j *Jar) AnnotateHAR(w io.Writer, r io.Reader) error {
+	return j.annotateHAR(w, r, time.Now())
+}
+
+// annotateHAR is like AnnotateHAR but takes the current time as a
+// parameter.
+func (j *Jar) annotateHAR(w io.Writer, r io.Reader, now time.Time) error {
+	// Decode only as far as the requests so that fields
+	// we don't know about are written out unchanged.
+	var har map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&har); err != nil {
+		return errgo.Notef(err, "cannot parse HAR")
+	}
+	var log map[string]json.RawMessage
+	if err := json.Unmarshal(har["log"], &log); err != nil {
+		return errgo.Notef(err, "cannot parse HAR log")
+	}
+	var entries []map[string]json.RawMessage
+	if err := json.Unmarshal(log["entries"], &entries); err != nil {
+		return errgo.Notef(err, "cannot parse HAR entries")
+	}
+	s := j.Snapshot()
+	for i, e := range entries {
+		var req map[string]json.RawMessage
+		if err := json.Unmarshal(e["request"], &req); err != nil {
+			return errgo.Notef(err, "entry %d: cannot parse request", i)
+		}
+		var rawURL string
+		if err := json.Unmarshal(req["url"], &rawURL); err != nil {
+			return errgo.Notef(err, "entry %d: cannot parse request URL", i)
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return errgo.Notef(err, "entry %d", i)
+		}
+		t := now
+		if started, ok := e["startedDateTime"]; ok {
+			var startedStr string
+			if err := json.Unmarshal(started, &startedStr); err != nil {
+				return errgo.Notef(err, "entry %d: cannot parse startedDateTime", i)
+			}
+			if t, err = time.Parse(time.RFC3339Nano, startedStr); err != nil {
+				return errgo.Newf("entry %d: invalid startedDateTime %q", i, startedStr)
+			}
+		}
+		cookies := []harNameValue{}
+		for _, c := range s.cookies(u, t) {
+			cookies = append(cookies, harNameValue{
+				Name:  c.Name,
+				Value: c.Value,
+			})
+		}
+		if req[harCookiesField], err = json.Marshal(cookies); err != nil {
+			return errgo.Mask(err)
+		}
+		if e["request"], err = json.Marshal(req); err != nil {
+			return errgo.Mask(err)
+		}
+	}
+	var err error
+	if log["entries"], err = json.Marshal(entries); err != nil {
+		return errgo.Mask(err)
+	}
+	if har["log"], err = json.Marshal(log); err != nil {
+		return errgo.Mask(err)
+	}
+	data, err := json.MarshalIndent(har, "", "  ")
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return errgo.Mask(err)
+}
@@ -0,0 +1,183 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// testHAR holds a HAR with its entries out of order,
+// as can happen with concurrent requests.
+const testHAR = `{
+	"log": {
+		"version": "1.2",
+		"creator": {"name": "test", "version": "1.0"},
+		"entries": [{
+			"startedDateTime": "2013-01-01T12:00:10.000Z",
+			"request": {"method": "GET", "url": "https://www.host.test/login", "headers": []},
+			"response": {
+				"status": 200,
+				"headers": [
+					{"name": "set-cookie", "value": "session=new; path=/\nuser=bob; domain=host.test; max-age=60"}
+				]
+			}
+		}, {
+			"startedDateTime": "2013-01-01T13:00:00.000+01:00",
+			"request": {"method": "GET", "url": "https://www.host.test/", "headers": []},
+			"response": {
+				"status": 200,
+				"headers": [
+					{"name": "Content-Type", "value": "text/html"},
+					{"name": "Set-Cookie", "value": "session=old; path=/"},
+					{"name": "Set-Cookie", "value": "user=alice; domain=host.test; max-age=60"},
+					{"name": "Set-Cookie", "value": "tracker=1; domain=other.test"}
+				]
+			}
+		}, {
+			"startedDateTime": "2013-01-01T12:00:20.000Z",
+			"request": {"method": "GET", "url": "https://www.host.test/logout", "headers": []},
+			"response": {
+				"status": 200,
+				"headers": [
+					{"name": "Set-Cookie", "value": "session=; path=/; max-age=-1"}
+				]
+			}
+		}]
+	}
+}`
+
+func TestReadHAR(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	err := jar.ReadHAR(strings.NewReader(testHAR))
+	c.Assert(err, qt.Equals, nil)
+	// The session cookie was deleted by the last request
+	// to be started, even though it is not the last entry.
+	c.Assert(allCookiesIncludingExpired(jar, tNow), qt.Equals, "session= user=bob")
+	c.Assert(queryJar(jar, "https://sub.host.test/", atTime(69)), qt.Equals, "user=bob")
+	c.Assert(queryJar(jar, "https://sub.host.test/", atTime(70)), qt.Equals, "")
+}
+
+var readHARErrorTests = []struct {
+	data   string
+	expect string
+}{{
+	data:   `{"log": `,
+	expect: `cannot parse HAR: unexpected EOF`,
+}, {
+	data:   `{"log": {"entries": [{"startedDateTime": "yesterday"}]}}`,
+	expect: `entry 0: invalid startedDateTime "yesterday"`,
+}, {
+	data:   `{"log": {"entries": [{"startedDateTime": "2013-01-01T12:00:00Z", "request": {"url": ":"}}]}}`,
+	expect: `entry 0: parse ":": missing protocol scheme`,
+}}
+
+func TestReadHARError(t *testing.T) {
+	c := qt.New(t)
+	for _, test := range readHARErrorTests {
+		jar := newTestJar("")
+		err := jar.ReadHAR(strings.NewReader(test.data))
+		c.Assert(err, qt.ErrorMatches, test.expect)
+		c.Assert(allCookiesIncludingExpired(jar, tNow), qt.Equals, "")
+	}
+}
+
+func TestAnnotateHAR(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	setCookies(jar, "https://www.host.test/", []string{
+		"a=1",
+		"b=2; path=/login",
+		"c=3; domain=host.test; secure",
+	}, tNow)
+	// Each request is annotated with the cookies as they were
+	// when it was started, so this cookie has expired by the
+	// time of the last request to be started.
+	setCookies(jar, "https://www.host.test/", []string{"d=4; max-age=15"}, tNow.Add(time.Millisecond))
+	before := jar.allEntries(true, tNow)
+
+	var buf bytes.Buffer
+	err := jar.annotateHAR(&buf, strings.NewReader(testHAR), atTime(1))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(jar.allEntries(true, tNow), qt.DeepEquals, before)
+
+	var har struct {
+		Log struct {
+			Version string
+			Creator map[string]string
+			Entries []struct {
+				StartedDateTime string
+				Request         struct {
+					Method     string
+					URL        string
+					JarCookies []harNameValue `json:"_jarCookies"`
+				}
+				Response struct {
+					Status  int
+					Headers []harNameValue
+				}
+			}
+		}
+	}
+	err = json.Unmarshal(buf.Bytes(), &har)
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(har.Log.Version, qt.Equals, "1.2")
+	c.Assert(har.Log.Creator, qt.DeepEquals, map[string]string{"name": "test", "version": "1.0"})
+	c.Assert(len(har.Log.Entries), qt.Equals, 3)
+	var got [][]harNameValue
+	for _, e := range har.Log.Entries {
+		c.Assert(e.Request.Method, qt.Equals, "GET")
+		c.Assert(e.Response.Status, qt.Equals, 200)
+		got = append(got, e.Request.JarCookies)
+	}
+	c.Assert(got, qt.DeepEquals, [][]harNameValue{
+		{{"b", "2"}, {"a", "1"}, {"c", "3"}, {"d", "4"}},
+		{{"a", "1"}, {"c", "3"}, {"d", "4"}},
+		{{"a", "1"}, {"c", "3"}},
+	})
+	c.Assert(har.Log.Entries[1].Response.Headers[0], qt.Equals, harNameValue{"Content-Type", "text/html"})
+}
+
+func TestAnnotateHARNoStartedDateTime(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	setCookies(jar, "https://www.host.test/", []string{"a=1; max-age=15"}, tNow)
+	// Without a start time, the cookies at the
+	// current time are used.
+	for _, test := range []struct {
+		now  time.Time
+		want int
+	}{{atTime(10), 1}, {atTime(20), 0}} {
+		var buf bytes.Buffer
+		err := jar.annotateHAR(&buf, strings.NewReader(`{"log": {"entries": [{"request": {"url": "https://www.host.test/"}}]}}`), test.now)
+		c.Assert(err, qt.Equals, nil)
+		var har struct {
+			Log struct {
+				Entries []struct {
+					Request struct {
+						JarCookies []harNameValue `json:"_jarCookies"`
+					}
+				}
+			}
+		}
+		err = json.Unmarshal(buf.Bytes(), &har)
+		c.Assert(err, qt.Equals, nil)
+		c.Assert(len(har.Log.Entries[0].Request.JarCookies), qt.Equals, test.want)
+	}
+}
+
+func TestAnnotateHARInvalidStartedDateTime(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	var buf bytes.Buffer
+	err := jar.annotateHAR(&buf, strings.NewReader(`{"log": {"entries": [{"startedDateTime": "yesterday", "request": {"url": "https://www.host.test/"}}]}}`), tNow)
+	c.Assert(err, qt.ErrorMatches, `entry 0: invalid startedDateTime "yesterday"`)
+}
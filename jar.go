@@ -10,7 +10,6 @@
 package cookiejar
 
 import (
-	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -66,27 +65,165 @@ type Options struct {
 
 	// Filename holds the file to use for storage of the cookies.
 	// If it is empty, the value of DefaultCookieFile will be used.
+	// It is ignored if Storage is non-nil.
 	Filename string
 
+	// Storage holds the storage to use for persisting the cookies.
+	// If it is nil, the cookies are stored in a file as
+	// specified by Filename.
+	Storage Storage
+
 	// NoPersist specifies whether no persistence should be used
-	// (useful for tests). If this is true, the value of Filename will be
-	// ignored.
+	// (useful for tests). If this is true, the values of Filename
+	// and Storage will be ignored.
 	NoPersist bool
+
+	// MaxCookiesPerDomain holds the maximum number of cookies
+	// that the jar will hold for any registrable domain (eTLD+1).
+	// When the limit is exceeded, expired cookies are evicted
+	// first, then the least recently accessed ones.
+	// If this is zero, there is no limit.
+	MaxCookiesPerDomain int
+
+	// MaxCookies holds the maximum number of cookies that the
+	// jar will hold in total. Cookies are evicted in the same
+	// way as for MaxCookiesPerDomain.
+	// If this is zero, there is no limit.
+	MaxCookies int
+
+	// AutoSaveDelay specifies that the jar should be saved
+	// automatically when its cookies change. The save happens
+	// once the cookies have not changed for the given delay, so
+	// that a burst of changes results in a single save. If this
+	// is zero, the jar is only saved when Save is called. See
+	// also Jar.Close.
+	AutoSaveDelay time.Duration
+
+	// OnSaveError is called with any error encountered when the
+	// jar is saved automatically. If it is nil, errors are logged.
+	OnSaveError func(error)
+
+	// Watch specifies that the jar should watch its storage for
+	// changes saved by other jars, including jars in other
+	// processes, and merge them in as they happen. When the
+	// cookies are stored in a file, changes are detected with
+	// file system notifications where available; otherwise the
+	// storage is polled. Call Jar.Close to stop watching.
+	Watch bool
+
+	// Policy holds a policy that decides whether cookies
+	// received by SetCookies may be stored in the jar.
+	// It is consulted before the jar's own rules are
+	// applied. If it is nil, all cookies that conform
+	// to those rules are accepted.
+	Policy Policy
+
+	// PersistSessionCookies specifies that session cookies
+	// (cookies without an expiry time) should be saved along
+	// with persistent cookies, so that they survive when the
+	// process exits. Call Jar.EndSession to remove them.
+	// All jars that share storage should use the same value.
+	PersistSessionCookies bool
+
+	// MaxSessionLifetime, if non-zero, holds the maximum time
+	// that a session cookie will be kept after it was last set.
+	// It is most useful when PersistSessionCookies is true.
+	MaxSessionLifetime time.Duration
+
+	// Encryption, if non-nil, provides the key used to encrypt
+	// the cookies in storage with AES-256-GCM. Unencrypted
+	// cookies already in storage are read and then encrypted
+	// when the jar is next saved. If the stored cookies cannot
+	// be decrypted with the key, New returns an error and Save
+	// does not overwrite them. All jars that share storage
+	// should use the same key.
+	Encryption KeyProvider
 }
 
 // Jar implements the http.CookieJar interface from the net/http package.
 type Jar struct {
-	// filename holds the file that the cookies were loaded from.
-	filename string
+	// storage holds the storage that the cookies were loaded from.
+	// It is nil if the cookies are not persisted.
+	storage Storage
 
 	psList PublicSuffixList
 
+	// maxPerDomain and maxCookies hold the limits on the number
+	// of cookies in the jar. See Options.MaxCookiesPerDomain
+	// and Options.MaxCookies.
+	maxPerDomain int
+	maxCookies   int
+
+	// autoSaveDelay and onSaveError hold the values of
+	// Options.AutoSaveDelay and Options.OnSaveError.
+	autoSaveDelay time.Duration
+	onSaveError   func(error)
+
+	// policy holds the value of Options.Policy.
+	policy Policy
+
+	// persistSession and maxSessionLifetime hold the values of
+	// Options.PersistSessionCookies and Options.MaxSessionLifetime.
+	persistSession     bool
+	maxSessionLifetime time.Duration
+
+	// keys holds the value of Options.Encryption.
+	keys KeyProvider
+
+	// autoSaveMu is held while the jar is being saved
+	// automatically.
+	autoSaveMu sync.Mutex
+
 	// mu locks the remaining fields.
 	mu sync.Mutex
 
 	// entries is a set of entries, keyed by their eTLD+1 and subkeyed by
 	// their name/domain/path.
 	entries map[string]map[string]entry
+
+	// dirty records whether entries holds changes that
+	// have not yet been saved.
+	dirty bool
+
+	// stored describes the data in storage as it was when
+	// it was last loaded or saved.
+	stored storedState
+
+	// salt and key hold the salt of the encrypted data in
+	// storage and the key derived from it, or nil if no
+	// encrypted data has been loaded or saved.
+	salt, key []byte
+
+	// saveTimer holds the timer that will trigger the next
+	// automatic save, or nil if there is none pending.
+	saveTimer *time.Timer
+
+	// closed records whether Close has been called.
+	closed bool
+
+	// subscribers holds the functions registered with Subscribe.
+	subscribers []subscriber
+
+	// nextSubscriberID holds the id of the next subscriber.
+	nextSubscriberID int
+
+	// pending holds events that have not yet been delivered
+	// to subscribers.
+	pending []Event
+
+	// notifying records whether events are currently being
+	// delivered to subscribers.
+	notifying bool
+
+	// parent holds the jar that an Overlay reads through to
+	// when j holds the overlay's changes. It is nil otherwise.
+	parent *Jar
+
+	// stopWatch and watchDone are used to stop the goroutine
+	// started by startWatching. They are nil if it is
+	// not running.
+	stopWatch chan struct{}
+	watchDone chan struct{}
 }
 
 var noOptions Options
@@ -111,15 +248,31 @@ func newAtTime(o *Options, now time.Time) (*Jar, error) {
 	if jar.psList = o.PublicSuffixList; jar.psList == nil {
 		jar.psList = publicsuffix.List
 	}
+	jar.maxPerDomain = o.MaxCookiesPerDomain
+	jar.maxCookies = o.MaxCookies
+	jar.autoSaveDelay = o.AutoSaveDelay
+	jar.onSaveError = o.OnSaveError
+	jar.policy = o.Policy
+	jar.persistSession = o.PersistSessionCookies
+	jar.maxSessionLifetime = o.MaxSessionLifetime
+	jar.keys = o.Encryption
 	if !o.NoPersist {
-		if jar.filename = o.Filename; jar.filename == "" {
-			jar.filename = DefaultCookieFile()
+		if jar.storage = o.Storage; jar.storage == nil {
+			filename := o.Filename
+			if filename == "" {
+				filename = DefaultCookieFile()
+			}
+			jar.storage = NewFileStorage(filename)
 		}
 		if err := jar.load(); err != nil {
 			return nil, errgo.Notef(err, "cannot load cookies")
 		}
 	}
 	jar.deleteExpired(now)
+	jar.evict(now)
+	if o.Watch && jar.storage != nil {
+		jar.startWatching()
+	}
 	return jar, nil
 }
 
@@ -161,10 +314,40 @@ type entry struct {
 	// when storing/loading cookies) we can still get the correct
 	// jar keys.
 	CanonicalHost string
+
+	// PartitionKey holds the top-level site (for example
+	// "https://example.com") that a partitioned cookie was set
+	// under. It is empty for cookies that are not partitioned.
+	PartitionKey string `json:",omitempty"`
+
+	// SameSite holds the SameSite attribute of the cookie:
+	// one of "Strict", "Lax" or "None", or empty if the
+	// attribute was not specified.
+	SameSite string `json:",omitempty"`
+}
+
+// cookie returns e as an http.Cookie with all the
+// attributes that http.Cookie can represent.
+func (e *entry) cookie() *http.Cookie {
+	return &http.Cookie{
+		Name:        e.Name,
+		Value:       e.Value,
+		Path:        e.Path,
+		Domain:      e.Domain,
+		Expires:     e.Expires,
+		Secure:      e.Secure,
+		HttpOnly:    e.HttpOnly,
+		SameSite:    sameSiteMode(e.SameSite),
+		Partitioned: e.PartitionKey != "",
+	}
 }
 
 // id returns the domain;path;name triple of e as an id.
+// For partitioned cookies, the partition key is appended.
 func (e *entry) id() string {
+	if e.PartitionKey != "" {
+		return id(e.Domain, e.Path, e.Name) + ";" + e.PartitionKey
+	}
 	return id(e.Domain, e.Path, e.Name)
 }
 
@@ -174,10 +357,44 @@ func id(domain, path, name string) string {
 }
 
 // shouldSend determines whether e's cookie qualifies to be included in a
-// request to host/path. It is the caller's responsibility to check if the
-// cookie is expired.
-func (e *entry) shouldSend(https bool, host, path string) bool {
-	return e.domainMatch(host) && e.pathMatch(path) && (https || !e.Secure)
+// request to host/path made from within the given top-level site.
+// It is the caller's responsibility to check if the cookie is expired.
+func (e *entry) shouldSend(https bool, host, path, site string) bool {
+	return e.domainMatch(host) && e.pathMatch(path) && (https || !e.Secure) && e.partitionMatch(site)
+}
+
+// partitionMatch reports whether e's cookie is available from within the
+// given top-level site. Cookies that are not partitioned are available
+// from any site.
+func (e *entry) partitionMatch(site string) bool {
+	return e.PartitionKey == "" || e.PartitionKey == site
+}
+
+// sameSiteMatch reports whether e's cookie qualifies to be included
+// in a request that is cross-site with respect to its top-level site,
+// made with the given method, as specified by RFC 6265bis section
+// 5.8.3. Lax cookies are sent with cross-site requests that use a
+// safe method because such requests are assumed to be top-level
+// navigations.
+func (e *entry) sameSiteMatch(method string) bool {
+	switch e.SameSite {
+	case "Strict":
+		return false
+	case "Lax":
+		return isSafeMethod(method)
+	}
+	return true
+}
+
+// isSafeMethod reports whether method is a "safe" HTTP method
+// as defined by RFC 7231 section 4.2.1. The empty string
+// means GET.
+func isSafeMethod(method string) bool {
+	switch method {
+	case "", "GET", "HEAD", "OPTIONS", "TRACE":
+		return true
+	}
+	return false
 }
 
 // domainMatch implements "domain-match" of RFC 6265 section 5.1.3.
@@ -254,6 +471,37 @@ func (j *Jar) Cookies(u *url.URL) (cookies []*http.Cookie) {
 
 // cookies is like Cookies but takes the current time as a parameter.
 func (j *Jar) cookies(u *url.URL, now time.Time) (cookies []*http.Cookie) {
+	return j.cookiesForRequest(u, nil, "", now)
+}
+
+// CookiesForRequest is like Cookies except that it also enforces the
+// SameSite attribute of the cookies as specified by RFC 6265bis. The
+// site argument holds the URL of the top-level site that the request
+// is made from (for example the page shown in a browser's address
+// bar) and method holds the HTTP method of the request.
+//
+// When u is not same-site with site, cookies with SameSite=Strict are
+// not returned and cookies with SameSite=Lax are only returned when
+// method is a safe method such as GET, because such requests are
+// treated as top-level navigations.
+//
+// Partitioned cookies are only returned when they were set from
+// within the same top-level site (see SetCookiesForRequest).
+//
+// If site is nil, u itself is treated as the top-level site, which is
+// also the behaviour of Cookies.
+func (j *Jar) CookiesForRequest(u, site *url.URL, method string) []*http.Cookie {
+	return j.cookiesForRequest(u, site, method, time.Now())
+}
+
+// lastAccessResolution holds how far a cookie's last access time
+// must move before the jar is marked as changed, so that cookies that
+// are read often do not cause a save each time.
+const lastAccessResolution = time.Minute
+
+// cookiesForRequest is like CookiesForRequest but takes the current time
+// as a parameter.
+func (j *Jar) cookiesForRequest(u, site *url.URL, method string, now time.Time) (cookies []*http.Cookie) {
 	if u.Scheme != "http" && u.Scheme != "https" {
 		return cookies
 	}
@@ -262,7 +510,13 @@ func (j *Jar) cookies(u *url.URL, now time.Time) (cookies []*http.Cookie) {
 		return cookies
 	}
 	key := jarKey(host, j.psList)
+	topLevel := j.siteOf(u)
+	if site != nil {
+		topLevel = j.siteOf(site)
+	}
+	crossSite := topLevel != j.siteOf(u)
 
+	defer j.notify()
 	j.mu.Lock()
 	defer j.mu.Unlock()
 
@@ -285,14 +539,23 @@ func (j *Jar) cookies(u *url.URL, now time.Time) (cookies []*http.Cookie) {
 			// we wouldn't know that the cookie had expired when
 			// we merge with another cookie jar.
 			if e.Value != "" {
+				j.event(CookieExpired, &e, u)
 				e.Value = ""
 				submap[id] = e
 			}
 			continue
 		}
-		if !e.shouldSend(https, host, path) {
+		if !e.shouldSend(https, host, path, topLevel) {
+			continue
+		}
+		if crossSite && !e.sameSiteMatch(method) {
 			continue
 		}
+		if now.Sub(e.LastAccess) >= lastAccessResolution {
+			// Make sure that the access time is saved,
+			// as eviction depends on it.
+			j.changed()
+		}
 		e.LastAccess = now
 		submap[id] = e
 		selected = append(selected, e)
@@ -306,20 +569,39 @@ func (j *Jar) cookies(u *url.URL, now time.Time) (cookies []*http.Cookie) {
 	return cookies
 }
 
+// siteOf returns the site of u as defined by RFC 6265bis section 5.2:
+// its scheme and registrable domain, for example "https://example.com".
+// Two URLs are same-site when their sites are equal. It returns the
+// empty string if u's host is invalid.
+func (j *Jar) siteOf(u *url.URL) string {
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return ""
+	}
+	return u.Scheme + "://" + jarKey(host, j.psList)
+}
+
 // AllCookies returns all cookies in the jar. The returned cookies will
-// have Domain, Expires, HttpOnly, Name, Secure, Path, and Value filled
+// have Domain, Expires, HttpOnly, Name, SameSite, Secure, Path, and Value filled
 // out. Expired cookies will not be returned. This function does not
-// modify the cookie jar.
+// modify the cookie jar. See Entries for a way to retrieve all the
+// information held about the cookies.
 func (j *Jar) AllCookies() (cookies []*http.Cookie) {
 	return j.allCookies(time.Now())
 }
 
 // allCookies is like AllCookies but takes the current time as a parameter.
 func (j *Jar) allCookies(now time.Time) []*http.Cookie {
-	var selected []entry
 	j.mu.Lock()
 	defer j.mu.Unlock()
-	for _, submap := range j.entries {
+	return liveCookies(j.entries, now)
+}
+
+// liveCookies returns all the unexpired cookies in the given entries
+// as described by Jar.AllCookies.
+func liveCookies(entries map[string]map[string]entry, now time.Time) []*http.Cookie {
+	var selected []entry
+	for _, submap := range entries {
 		for _, e := range submap {
 			if !e.Expires.After(now) {
 				// Do not return expired cookies.
@@ -331,40 +613,55 @@ func (j *Jar) allCookies(now time.Time) []*http.Cookie {
 
 	sort.Sort(byCanonicalHost{byPathLength(selected)})
 	cookies := make([]*http.Cookie, len(selected))
-	for i, e := range selected {
+	for i := range selected {
 		// Note: The returned cookies do not contain sufficient
 		// information to recreate the database.
-		cookies[i] = &http.Cookie{
-			Name:     e.Name,
-			Value:    e.Value,
-			Path:     e.Path,
-			Domain:   e.Domain,
-			Expires:  e.Expires,
-			Secure:   e.Secure,
-			HttpOnly: e.HttpOnly,
-		}
+		cookies[i] = selected[i].cookie()
 	}
 
 	return cookies
 }
 
 // RemoveCookie removes the cookie matching the name, domain and path
-// specified by c.
+// specified by c. If c.Partitioned is set, the matching cookies
+// in all partitions are removed.
 func (j *Jar) RemoveCookie(c *http.Cookie) {
+	defer j.notify()
 	j.mu.Lock()
 	defer j.mu.Unlock()
-	id := id(c.Domain, c.Path, c.Name)
+	now := time.Now()
 	key := jarKey(c.Domain, j.psList)
-	if e, ok := j.entries[key][id]; ok {
-		e.Value = ""
-		e.Expires = time.Now().Add(-1 * time.Second)
-		j.entries[key][id] = e
+	submap := j.entries[key]
+	for id, e := range submap {
+		if e.Name == c.Name && e.Domain == c.Domain && e.Path == c.Path && (e.PartitionKey != "") == c.Partitioned {
+			j.remove(submap, id, now)
+		}
 	}
 }
 
+// remove marks the entry with the given id in submap as removed.
+// We can't delete the entry itself because then we wouldn't know
+// that the cookie had been removed when we merge with another
+// cookie jar.
+func (j *Jar) remove(submap map[string]entry, id string, now time.Time) {
+	e := submap[id]
+	if e.Expires.After(now) {
+		j.event(CookieDeleted, &e, nil)
+	}
+	// Save some space by deleting the value.
+	e.Value = ""
+	e.Expires = now.Add(-1 * time.Second)
+	// Mark the removal as an update so that it takes precedence
+	// over the cookie when merged into other jars.
+	e.Updated = now
+	submap[id] = e
+	j.changed()
+}
+
 // merge merges all the given entries into j. More recently changed
-// cookies take precedence over older ones.
-func (j *Jar) merge(entries []entry) {
+// cookies take precedence over older ones. It reports whether
+// any entries were changed.
+func (j *Jar) merge(entries []entry) (changed bool) {
 	for _, e := range entries {
 		if e.CanonicalHost == "" {
 			continue
@@ -376,13 +673,18 @@ func (j *Jar) merge(entries []entry) {
 			j.entries[key] = map[string]entry{
 				id: e,
 			}
+			j.event(CookieMerged, &e, nil)
+			changed = true
 			continue
 		}
 		oldEntry, ok := submap[id]
 		if !ok || e.Updated.After(oldEntry.Updated) {
 			submap[id] = e
+			j.event(CookieMerged, &e, nil)
+			changed = true
 		}
 	}
+	return changed
 }
 
 var expiryRemovalDuration = 24 * time.Hour
@@ -393,7 +695,7 @@ var expiryRemovalDuration = 24 * time.Hour
 func (j *Jar) deleteExpired(now time.Time) {
 	for tld, submap := range j.entries {
 		for id, e := range submap {
-			if !e.Expires.After(now) && !e.Updated.Add(expiryRemovalDuration).After(now) {
+			if !e.Expires.After(now) && !e.isRecentTombstone(now) {
 				delete(submap, id)
 			}
 		}
@@ -403,6 +705,89 @@ func (j *Jar) deleteExpired(now time.Time) {
 	}
 }
 
+// evictionCandidate holds an entry that might be evicted
+// from the jar, along with its location in j.entries.
+type evictionCandidate struct {
+	key, id string
+	e       entry
+}
+
+// evict removes entries from the jar while it holds more than the
+// number of cookies allowed by j.maxPerDomain or j.maxCookies, as
+// permitted by RFC 6265 section 5.3 step 12.
+//
+// Evicted entries are deleted outright rather than being marked as
+// expired, so they do not themselves count towards the limits.
+// Entries that have recently expired are kept so that they can
+// still delete copies of their cookies held elsewhere (see
+// deleteExpired), and are not counted either.
+func (j *Jar) evict(now time.Time) {
+	if j.maxPerDomain > 0 {
+		for key, submap := range j.entries {
+			if len(submap) <= j.maxPerDomain {
+				continue
+			}
+			var candidates []evictionCandidate
+			for id, e := range submap {
+				if !e.isRecentTombstone(now) {
+					candidates = append(candidates, evictionCandidate{key, id, e})
+				}
+			}
+			if n := len(candidates) - j.maxPerDomain; n > 0 {
+				j.evictOldest(candidates, n, now)
+			}
+		}
+	}
+	if j.maxCookies > 0 {
+		var candidates []evictionCandidate
+		for key, submap := range j.entries {
+			for id, e := range submap {
+				if !e.isRecentTombstone(now) {
+					candidates = append(candidates, evictionCandidate{key, id, e})
+				}
+			}
+		}
+		if n := len(candidates) - j.maxCookies; n > 0 {
+			j.evictOldest(candidates, n, now)
+		}
+	}
+}
+
+// isRecentTombstone reports whether e has expired too recently
+// for deleteExpired to delete it.
+func (e *entry) isRecentTombstone(now time.Time) bool {
+	return !e.Expires.After(now) && e.Updated.Add(expiryRemovalDuration).After(now)
+}
+
+// evictOldest deletes n of the given candidates from the jar. Expired
+// entries are deleted first, then the least recently accessed ones.
+func (j *Jar) evictOldest(candidates []evictionCandidate, n int, now time.Time) {
+	sort.Slice(candidates, func(i, k int) bool {
+		e0, e1 := &candidates[i].e, &candidates[k].e
+		if expired0, expired1 := !e0.Expires.After(now), !e1.Expires.After(now); expired0 != expired1 {
+			return expired0
+		}
+		if !e0.LastAccess.Equal(e1.LastAccess) {
+			return e0.LastAccess.Before(e1.LastAccess)
+		}
+		if !e0.Creation.Equal(e1.Creation) {
+			return e0.Creation.Before(e1.Creation)
+		}
+		return candidates[i].id < candidates[k].id
+	})
+	for _, c := range candidates[:n] {
+		if c.e.Expires.After(now) {
+			j.event(CookieDeleted, &c.e, nil)
+		}
+		j.changed()
+		submap := j.entries[c.key]
+		delete(submap, c.id)
+		if len(submap) == 0 {
+			delete(j.entries, c.key)
+		}
+	}
+}
+
 // RemoveAllHost removes any cookies from the jar that were set for the given host.
 func (j *Jar) RemoveAllHost(host string) {
 	host, err := canonicalHost(host)
@@ -411,38 +796,44 @@ func (j *Jar) RemoveAllHost(host string) {
 	}
 	key := jarKey(host, j.psList)
 
+	defer j.notify()
 	j.mu.Lock()
 	defer j.mu.Unlock()
 
-	expired := time.Now().Add(-1 * time.Second)
+	now := time.Now()
 	submap := j.entries[key]
 	for id, e := range submap {
 		if e.CanonicalHost == host {
-			// Save some space by deleting the value when the cookie
-			// expires. We can't delete the cookie itself because then
-			// we wouldn't know that the cookie had expired when
-			// we merge with another cookie jar.
-			e.Value = ""
-			e.Expires = expired
-			submap[id] = e
+			j.remove(submap, id, now)
 		}
 	}
 }
 
 // RemoveAll removes all the cookies from the jar.
 func (j *Jar) RemoveAll() {
-	expired := time.Now().Add(-1 * time.Second)
+	now := time.Now()
+	defer j.notify()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, submap := range j.entries {
+		for id := range submap {
+			j.remove(submap, id, now)
+		}
+	}
+}
+
+// EndSession removes all session cookies (cookies without an expiry
+// time) from the jar, as happens when a browser is restarted.
+func (j *Jar) EndSession() {
+	now := time.Now()
+	defer j.notify()
 	j.mu.Lock()
 	defer j.mu.Unlock()
 	for _, submap := range j.entries {
 		for id, e := range submap {
-			// Save some space by deleting the value when the cookie
-			// expires. We can't delete the cookie itself because then
-			// we wouldn't know that the cookie had expired when
-			// we merge with another cookie jar.
-			e.Value = ""
-			e.Expires = expired
-			submap[id] = e
+			if !e.Persistent && e.Expires.After(now) {
+				j.remove(submap, id, now)
+			}
 		}
 	}
 }
@@ -454,47 +845,215 @@ func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
 	j.setCookies(u, cookies, time.Now())
 }
 
-// setCookies is like SetCookies but takes the current time as parameter.
-func (j *Jar) setCookies(u *url.URL, cookies []*http.Cookie, now time.Time) {
+// setCookies is like SetCookiesWithResults but takes the current time
+// as parameter.
+func (j *Jar) setCookies(u *url.URL, cookies []*http.Cookie, now time.Time) []SetCookieResult {
+	return j.setCookiesForRequest(u, nil, cookies, now)
+}
+
+// Outcome describes what happened to a cookie passed
+// to SetCookiesWithResults.
+type Outcome int
+
+const (
+	// OutcomeStored means that the cookie was stored
+	// and there was no previous cookie with the same
+	// name, domain and path.
+	OutcomeStored Outcome = iota + 1
+
+	// OutcomeUpdated means that the cookie was stored,
+	// replacing a previous cookie with the same name,
+	// domain and path.
+	OutcomeUpdated
+
+	// OutcomeDeleted means that the cookie had already
+	// expired, so any previous cookie with the same
+	// name, domain and path was deleted.
+	OutcomeDeleted
+
+	// OutcomeRejected means that the cookie was not
+	// stored because it broke one of the jar's rules.
+	OutcomeRejected
+)
+
+var outcomeNames = map[Outcome]string{
+	OutcomeStored:   "stored",
+	OutcomeUpdated:  "updated",
+	OutcomeDeleted:  "deleted",
+	OutcomeRejected: "rejected",
+}
+
+// String implements fmt.Stringer.
+func (o Outcome) String() string {
+	if name, ok := outcomeNames[o]; ok {
+		return name
+	}
+	return fmt.Sprintf("Outcome(%d)", int(o))
+}
+
+// SetCookieResult holds the result of setting a cookie.
+type SetCookieResult struct {
+	// Cookie holds the cookie as passed to SetCookiesWithResults.
+	Cookie *http.Cookie
+
+	// Outcome holds what happened to the cookie.
+	Outcome Outcome
+
+	// Err holds a *RejectError describing why the cookie
+	// was rejected when Outcome is OutcomeRejected.
+	Err error
+}
+
+// SetCookiesWithResults is like SetCookies except that it returns
+// the result of setting each of the given cookies, in the same
+// order, including the reason that any cookie was rejected.
+func (j *Jar) SetCookiesWithResults(u *url.URL, cookies []*http.Cookie) []SetCookieResult {
+	return j.setCookies(u, cookies, time.Now())
+}
+
+// rejectAll returns results that reject all the given cookies
+// because of the given rule.
+func rejectAll(cookies []*http.Cookie, rule Rule) []SetCookieResult {
+	results := make([]SetCookieResult, len(cookies))
+	for i, c := range cookies {
+		results[i] = SetCookieResult{
+			Cookie:  c,
+			Outcome: OutcomeRejected,
+			Err:     &RejectError{Rule: rule},
+		}
+	}
+	return results
+}
+
+// SetCookiesForRequest is like SetCookies except that it takes the URL
+// of the top-level site that the request was made from (for example
+// the page shown in a browser's address bar). Cookies with the
+// Partitioned attribute are stored in a partition keyed by that site
+// and are only returned by CookiesForRequest for requests made from
+// within the same site.
+//
+// If site is nil, u itself is treated as the top-level site, which is
+// also the behaviour of SetCookies.
+func (j *Jar) SetCookiesForRequest(u, site *url.URL, cookies []*http.Cookie) {
+	j.setCookiesForRequest(u, site, cookies, time.Now())
+}
+
+// setCookiesForRequest is like SetCookiesForRequest but takes the
+// current time as parameter. It returns the result of setting each
+// cookie as described by SetCookiesWithResults.
+func (j *Jar) setCookiesForRequest(u, site *url.URL, cookies []*http.Cookie, now time.Time) []SetCookieResult {
 	if len(cookies) == 0 {
-		return
+		return nil
 	}
 	if u.Scheme != "http" && u.Scheme != "https" {
 		// TODO is this really correct? It might be nice to send
 		// cookies to websocket connections, for example.
-		return
+		return rejectAll(cookies, RuleScheme)
 	}
 	host, err := canonicalHost(u.Host)
 	if err != nil {
-		return
+		return rejectAll(cookies, RuleHost)
 	}
 	key := jarKey(host, j.psList)
 	defPath := defaultPath(u.Path)
+	https := u.Scheme == "https"
+	if site == nil {
+		site = u
+	}
+	partition := j.siteOf(site)
+
+	// Consult the policy before acquiring the lock
+	// so that it is free to use the jar.
+	results := make([]SetCookieResult, len(cookies))
+	checked := make([]*http.Cookie, len(cookies))
+	for i, cookie := range cookies {
+		results[i].Cookie = cookie
+		c, err := j.checkPolicy(u, cookie)
+		if err != nil {
+			results[i].Outcome = OutcomeRejected
+			results[i].Err = err
+			continue
+		}
+		checked[i] = c
+	}
 
+	defer j.notify()
 	j.mu.Lock()
 	defer j.mu.Unlock()
 
 	submap := j.entries[key]
-	for _, cookie := range cookies {
-		e, err := j.newEntry(cookie, now, defPath, host)
+	for i, c := range checked {
+		if c == nil {
+			continue
+		}
+		e, err := j.newEntry(c, now, defPath, host, https)
 		if err != nil {
+			results[i].Outcome = OutcomeRejected
+			results[i].Err = err
 			continue
 		}
 		e.CanonicalHost = host
+		if c.Partitioned {
+			if partition == "" {
+				// Storing the cookie unpartitioned would
+				// make it visible under every site.
+				results[i].Outcome = OutcomeRejected
+				results[i].Err = errInvalidSite
+				continue
+			}
+			e.PartitionKey = partition
+		}
 		id := e.id()
 		if submap == nil {
 			submap = make(map[string]entry)
 			j.entries[key] = submap
 		}
-		if old, ok := submap[id]; ok {
+		old, ok := submap[id]
+		if !ok && j.parent != nil {
+			old, ok = j.parent.lookup(key, id)
+		}
+		if ok {
 			e.Creation = old.Creation
 		} else {
 			e.Creation = now
 		}
 		e.Updated = now
 		e.LastAccess = now
+		oldLive := ok && old.Expires.After(now)
+		switch {
+		case !e.Expires.After(now):
+			results[i].Outcome = OutcomeDeleted
+			if oldLive {
+				j.event(CookieDeleted, &old, u)
+			}
+		case oldLive:
+			results[i].Outcome = OutcomeUpdated
+			j.event(CookieUpdated, &e, u)
+		default:
+			results[i].Outcome = OutcomeStored
+			j.event(CookieAdded, &e, u)
+		}
 		submap[id] = e
+		j.changed()
 	}
+	j.evict(now)
+	return results
+}
+
+// changed records that the cookies in j have changed since they were
+// last saved, scheduling an automatic save if required. It must be
+// called with j.mu held.
+func (j *Jar) changed() {
+	j.dirty = true
+	if j.autoSaveDelay == 0 || j.storage == nil || j.closed {
+		return
+	}
+	if j.saveTimer != nil {
+		// Postpone the save until the changes stop.
+		j.saveTimer.Reset(j.autoSaveDelay)
+		return
+	}
+	j.saveTimer = time.AfterFunc(j.autoSaveDelay, j.autoSave)
 }
 
 // canonicalHost strips port from host if present and returns the canonicalized
@@ -578,14 +1137,16 @@ func defaultPath(path string) string {
 // newEntry creates an entry from a http.Cookie c. now is the current
 // time and is compared to c.Expires to determine deletion of c. defPath
 // and host are the default-path and the canonical host name of the URL
-// c was received from.
+// c was received from, and https reports whether that URL used a secure
+// scheme.
 //
 // The returned entry should be removed if its expiry time is in the
 // past. In this case, e may be incomplete, but it will be valid to call
 // e.id (which depends on e's Name, Domain and Path).
 //
-// A malformed c.Domain will result in an error.
-func (j *Jar) newEntry(c *http.Cookie, now time.Time, defPath, host string) (e entry, err error) {
+// A cookie that breaks any of the jar's rules, such as one with a
+// malformed c.Domain, will result in a *RejectError.
+func (j *Jar) newEntry(c *http.Cookie, now time.Time, defPath, host string, https bool) (e entry, err error) {
 	e.Name = c.Name
 	if c.Path == "" || c.Path[0] != '/' {
 		e.Path = defPath
@@ -597,6 +1158,16 @@ func (j *Jar) newEntry(c *http.Cookie, now time.Time, defPath, host string) (e e
 	if err != nil {
 		return e, err
 	}
+	// See RFC 6265bis section 5.7 steps 20 and 21.
+	if hasPrefixFold(c.Name, "__Secure-") && !(https && c.Secure) {
+		return e, errSecurePrefix
+	}
+	if hasPrefixFold(c.Name, "__Host-") && !(https && c.Secure && c.Domain == "" && c.Path == "/") {
+		return e, errHostPrefix
+	}
+	if c.Partitioned && !c.Secure {
+		return e, errInsecurePartitioned
+	}
 	// MaxAge takes precedence over Expires.
 	if c.MaxAge != 0 {
 		e.Persistent = true
@@ -606,6 +1177,9 @@ func (j *Jar) newEntry(c *http.Cookie, now time.Time, defPath, host string) (e e
 		}
 	} else if c.Expires.IsZero() {
 		e.Expires = endOfTime
+		if j.maxSessionLifetime > 0 {
+			e.Expires = now.Add(j.maxSessionLifetime)
+		}
 	} else {
 		e.Persistent = true
 		e.Expires = c.Expires
@@ -617,16 +1191,164 @@ func (j *Jar) newEntry(c *http.Cookie, now time.Time, defPath, host string) (e e
 	e.Value = c.Value
 	e.Secure = c.Secure
 	e.HttpOnly = c.HttpOnly
+	e.SameSite = sameSiteAttr(c.SameSite)
+	if e.SameSite == "None" && !e.Secure {
+		// See RFC 6265bis section 5.7 step 18.
+		return e, errInsecureSameSiteNone
+	}
 
 	return e, nil
 }
 
+// sameSiteAttr returns the value to store in entry.SameSite
+// for the given SameSite mode.
+func sameSiteAttr(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return ""
+}
+
+// sameSiteMode is the inverse of sameSiteAttr.
+func sameSiteMode(attr string) http.SameSite {
+	switch attr {
+	case "Strict":
+		return http.SameSiteStrictMode
+	case "Lax":
+		return http.SameSiteLaxMode
+	case "None":
+		return http.SameSiteNoneMode
+	}
+	return 0
+}
+
+// Rule identifies a rule that caused the jar to reject a cookie.
+type Rule int
+
+const (
+	// RuleScheme is broken when a cookie is received
+	// from a URL whose scheme is not http or https.
+	RuleScheme Rule = iota + 1
+
+	// RuleHost is broken when a cookie is received from
+	// a URL whose host name is invalid, or when a cookie
+	// with the Partitioned attribute is received for a
+	// top-level site whose host name is invalid.
+	RuleHost
+
+	// RuleMalformedDomain is broken when a cookie's Domain
+	// attribute is malformed.
+	RuleMalformedDomain
+
+	// RuleIPHost is broken when a cookie received from
+	// an IP address has a Domain attribute.
+	RuleIPHost
+
+	// RulePublicSuffix is broken when a cookie's Domain
+	// attribute is a public suffix such as "com".
+	RulePublicSuffix
+
+	// RuleDomainMismatch is broken when a cookie's Domain
+	// attribute does not domain-match the host that it
+	// was received from.
+	RuleDomainMismatch
+
+	// RuleSameSiteNone is broken when a cookie with
+	// SameSite=None does not have the Secure attribute.
+	RuleSameSiteNone
+
+	// RuleSecurePrefix is broken when a cookie whose name
+	// starts with "__Secure-" does not have the Secure
+	// attribute or was not received over https.
+	RuleSecurePrefix
+
+	// RuleHostPrefix is broken when a cookie whose name
+	// starts with "__Host-" does not meet the requirements
+	// of RuleSecurePrefix, has a Domain attribute or has a
+	// Path attribute other than "/".
+	RuleHostPrefix
+
+	// RulePartitioned is broken when a cookie with the
+	// Partitioned attribute does not have the Secure
+	// attribute.
+	RulePartitioned
+
+	// RulePolicy is broken when a cookie is rejected
+	// by the jar's Policy.
+	RulePolicy
+)
+
+var ruleMessages = map[Rule]string{
+	RuleScheme:          "URL scheme is not http or https",
+	RuleHost:            "invalid host name",
+	RuleMalformedDomain: "malformed cookie domain attribute",
+	RuleIPHost:          "no host name available (IP only)",
+	RulePublicSuffix:    "cookie domain attribute is a public suffix",
+	RuleDomainMismatch:  "illegal cookie domain attribute",
+	RuleSameSiteNone:    "SameSite=None cookie without Secure attribute",
+	RuleSecurePrefix:    "__Secure- cookie not set securely",
+	RuleHostPrefix:      "__Host- cookie not set securely as a host cookie with path /",
+	RulePartitioned:     "Partitioned cookie without Secure attribute",
+	RulePolicy:          "cookie rejected by policy",
+}
+
+// String implements fmt.Stringer.
+func (r Rule) String() string {
+	if msg, ok := ruleMessages[r]; ok {
+		return msg
+	}
+	return fmt.Sprintf("Rule(%d)", int(r))
+}
+
+// RejectError is the error reported by SetCookiesWithResults
+// when a cookie is rejected.
+type RejectError struct {
+	// Rule holds the rule that the cookie broke.
+	Rule Rule
+
+	// Err holds the error returned by the jar's Policy
+	// when Rule is RulePolicy.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *RejectError) Error() string {
+	if e.Err != nil {
+		return "cookiejar: " + e.Rule.String() + ": " + e.Err.Error()
+	}
+	return "cookiejar: " + e.Rule.String()
+}
+
+// Cause returns the error returned by the jar's Policy, if any.
+// This makes RejectError compatible with errgo.Cause.
+func (e *RejectError) Cause() error {
+	return e.Err
+}
+
 var (
-	errIllegalDomain   = errors.New("cookiejar: illegal cookie domain attribute")
-	errMalformedDomain = errors.New("cookiejar: malformed cookie domain attribute")
-	errNoHostname      = errors.New("cookiejar: no host name available (IP only)")
+	errIllegalDomain   = &RejectError{Rule: RuleDomainMismatch}
+	errPublicSuffix    = &RejectError{Rule: RulePublicSuffix}
+	errMalformedDomain = &RejectError{Rule: RuleMalformedDomain}
+	errNoHostname      = &RejectError{Rule: RuleIPHost}
+
+	errInsecureSameSiteNone = &RejectError{Rule: RuleSameSiteNone}
+	errSecurePrefix         = &RejectError{Rule: RuleSecurePrefix}
+	errHostPrefix           = &RejectError{Rule: RuleHostPrefix}
+	errInsecurePartitioned  = &RejectError{Rule: RulePartitioned}
+	errInvalidSite          = &RejectError{Rule: RuleHost}
 )
 
+// hasPrefixFold reports whether s begins with prefix,
+// ignoring case.
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
 // endOfTime is the time when session (non-persistent) cookies expire.
 // This instant is representable in most date/time formats (not just
 // Go's time.Time) and should be far enough in the future.
@@ -679,7 +1401,7 @@ func (j *Jar) domainAndType(host, domain string) (string, bool, error) {
 				// with a domain attribute is a host cookie.
 				return host, true, nil
 			}
-			return "", false, errIllegalDomain
+			return "", false, errPublicSuffix
 		}
 	}
 
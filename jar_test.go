@@ -293,9 +293,9 @@ var domainAndTypeTests = [...]struct {
 	{"www.example.com", ".", "", false, errMalformedDomain},
 	{"www.example.com", "..", "", false, errMalformedDomain},
 	{"www.example.com", "other.com", "", false, errIllegalDomain},
-	{"www.example.com", "com", "", false, errIllegalDomain},
-	{"www.example.com", ".com", "", false, errIllegalDomain},
-	{"foo.bar.co.uk", ".co.uk", "", false, errIllegalDomain},
+	{"www.example.com", "com", "", false, errPublicSuffix},
+	{"www.example.com", ".com", "", false, errPublicSuffix},
+	{"foo.bar.co.uk", ".co.uk", "", false, errPublicSuffix},
 	{"127.www.0.0.1", "127.0.0.1", "", false, errIllegalDomain},
 	{"com", "", "com", true, nil},
 	{"com", "com", "com", true, nil},
@@ -325,6 +325,69 @@ func TestDomainAndType(t *testing.T) {
 	}
 }
 
+var setCookiesWithResultsTests = []struct {
+	fromURL     string
+	cookie      string
+	wantOutcome Outcome
+	wantRule    Rule
+}{
+	{"http://www.host.test/", "new=1", OutcomeStored, 0},
+	{"http://www.host.test/", "existing=2", OutcomeUpdated, 0},
+	{"http://www.host.test/", "existing=; max-age=-1", OutcomeDeleted, 0},
+	{"http://www.host.test/", "other=; max-age=-1", OutcomeDeleted, 0},
+	{"ftp://www.host.test/", "a=1", OutcomeRejected, RuleScheme},
+	{"http://www.host.test/", "a=1; domain=..host.test", OutcomeRejected, RuleMalformedDomain},
+	{"http://127.0.0.1/", "a=1; domain=127.0.0.1", OutcomeRejected, RuleIPHost},
+	{"http://www.host.co.uk/", "a=1; domain=co.uk", OutcomeRejected, RulePublicSuffix},
+	{"http://www.host.test/", "a=1; domain=other.test", OutcomeRejected, RuleDomainMismatch},
+	{"https://www.host.test/", "a=1; samesite=none", OutcomeRejected, RuleSameSiteNone},
+	{"http://www.host.test/", "__Secure-a=1; secure", OutcomeRejected, RuleSecurePrefix},
+	{"https://www.host.test/", "__Host-a=1; secure; path=/foo", OutcomeRejected, RuleHostPrefix},
+	{"https://www.host.test/", "a=1; partitioned", OutcomeRejected, RulePartitioned},
+}
+
+func TestSetCookiesWithResults(t *testing.T) {
+	for _, test := range setCookiesWithResultsTests {
+		jar := newTestJar("")
+		setCookies(jar, "http://www.host.test/", []string{"existing=1"}, tNow)
+		cookie := (&http.Response{Header: http.Header{"Set-Cookie": {test.cookie}}}).Cookies()[0]
+		u, err := url.Parse(test.fromURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results := jar.setCookies(u, []*http.Cookie{cookie}, tNow)
+		if len(results) != 1 {
+			t.Fatalf("%s %q: got %d results, want 1", test.fromURL, test.cookie, len(results))
+		}
+		r := results[0]
+		if r.Cookie != cookie {
+			t.Errorf("%s %q: result has wrong cookie %v", test.fromURL, test.cookie, r.Cookie)
+		}
+		if r.Outcome != test.wantOutcome {
+			t.Errorf("%s %q: got outcome %v, want %v", test.fromURL, test.cookie, r.Outcome, test.wantOutcome)
+		}
+		if test.wantRule == 0 {
+			if r.Err != nil {
+				t.Errorf("%s %q: unexpected error %v", test.fromURL, test.cookie, r.Err)
+			}
+			continue
+		}
+		rerr, ok := r.Err.(*RejectError)
+		if !ok || rerr.Rule != test.wantRule {
+			t.Errorf("%s %q: got error %#v, want rule %v", test.fromURL, test.cookie, r.Err, test.wantRule)
+		}
+	}
+}
+
+func TestSetCookiesWithResultsInvalidHost(t *testing.T) {
+	jar := newTestJar("")
+	u := &url.URL{Scheme: "http", Host: "[::1]:80:"}
+	results := jar.SetCookiesWithResults(u, []*http.Cookie{{Name: "a", Value: "1"}})
+	if len(results) != 1 || results[0].Outcome != OutcomeRejected || results[0].Err.Error() != "cookiejar: invalid host name" {
+		t.Errorf("unexpected results %#v", results)
+	}
+}
+
 // basicsTests contains fundamental tests. Each jarTest has to be performed on
 // a fresh, empty Jar.
 var basicsTests = [...]jarTest{
@@ -1035,6 +1098,108 @@ func TestChromiumDeletion(t *testing.T) {
 	}
 }
 
+// cookiePrefixTests tests the handling of the "__Secure-" and "__Host-"
+// cookie name prefixes as described in RFC 6265bis section 4.1.3.
+// Each jarTest has to be performed on a fresh, empty Jar.
+var cookiePrefixTests = [...]jarTest{
+	{
+		"__Secure- cookie set securely.",
+		"https://www.host.test/",
+		[]string{"__Secure-a=1; secure", "__Secure-b=2; secure; domain=host.test"},
+		"__Secure-a=1 __Secure-b=2",
+		[]query{
+			{"https://www.host.test", "__Secure-a=1 __Secure-b=2"},
+			{"http://www.host.test", ""},
+		},
+	},
+	{
+		"__Secure- cookie without secure attribute.",
+		"https://www.host.test/",
+		[]string{"__Secure-a=1"},
+		"",
+		[]query{
+			{"https://www.host.test", ""},
+		},
+	},
+	{
+		"__Secure- cookie from insecure origin.",
+		"http://www.host.test/",
+		[]string{"__Secure-a=1; secure"},
+		"",
+		[]query{
+			{"https://www.host.test", ""},
+		},
+	},
+	{
+		"Prefixes are matched case-insensitively.",
+		"http://www.host.test/",
+		[]string{"__secure-a=1; secure", "__HOST-b=2; secure; path=/"},
+		"",
+		[]query{
+			{"https://www.host.test", ""},
+		},
+	},
+	{
+		"__Host- cookie set securely.",
+		"https://www.host.test/foo/bar",
+		[]string{"__Host-a=1; secure; path=/"},
+		"__Host-a=1",
+		[]query{
+			{"https://www.host.test", "__Host-a=1"},
+			{"https://other.host.test", ""},
+		},
+	},
+	{
+		"__Host- cookie with a domain attribute.",
+		"https://www.host.test/",
+		[]string{
+			"__Host-a=1; secure; path=/; domain=host.test",
+			"__Host-b=2; secure; path=/; domain=www.host.test",
+		},
+		"",
+		[]query{
+			{"https://www.host.test", ""},
+		},
+	},
+	{
+		"__Host- cookie with a path other than /.",
+		"https://www.host.test/",
+		[]string{
+			"__Host-a=1; secure; path=/foo",
+			"__Host-b=2; secure",
+		},
+		"",
+		[]query{
+			{"https://www.host.test/foo", ""},
+		},
+	},
+	{
+		"__Host- cookie from insecure origin.",
+		"http://www.host.test/",
+		[]string{"__Host-a=1; secure; path=/", "__Host-b=2; path=/"},
+		"",
+		[]query{
+			{"https://www.host.test", ""},
+		},
+	},
+	{
+		"Names that merely contain a prefix are not restricted.",
+		"http://www.host.test/",
+		[]string{"x__Host-a=1; domain=host.test", "__Securea=2"},
+		"__Securea=2 x__Host-a=1",
+		[]query{
+			{"http://www.host.test", "__Securea=2 x__Host-a=1"},
+		},
+	},
+}
+
+func TestCookiePrefixes(t *testing.T) {
+	for _, test := range cookiePrefixTests {
+		jar := newTestJar("")
+		test.run(t, jar)
+	}
+}
+
 // domainHandlingTests tests and documents the rules for domain handling.
 // Each test must be performed on an empty new Jar.
 var domainHandlingTests = [...]jarTest{
@@ -1209,6 +1374,224 @@ func TestDomainHandling(t *testing.T) {
 	}
 }
 
+var sameSiteTests = []struct {
+	description string
+	site        string // top-level site of the request; empty means none
+	method      string
+	toURL       string
+	want        string
+}{{
+	description: "no site",
+	toURL:       "https://www.host.test/",
+	want:        "D=d L=l N=n S=s",
+}, {
+	description: "same host",
+	site:        "https://www.host.test/",
+	method:      "POST",
+	toURL:       "https://www.host.test/",
+	want:        "D=d L=l N=n S=s",
+}, {
+	description: "same registrable domain",
+	site:        "https://other.host.test/page",
+	method:      "POST",
+	toURL:       "https://www.host.test/",
+	want:        "D=d L=l N=n S=s",
+}, {
+	description: "cross-site GET",
+	site:        "https://www.other.test/",
+	method:      "GET",
+	toURL:       "https://www.host.test/",
+	want:        "D=d L=l N=n",
+}, {
+	description: "cross-site request with empty method",
+	site:        "https://www.other.test/",
+	toURL:       "https://www.host.test/",
+	want:        "D=d L=l N=n",
+}, {
+	description: "cross-site POST",
+	site:        "https://www.other.test/",
+	method:      "POST",
+	toURL:       "https://www.host.test/",
+	want:        "D=d N=n",
+}, {
+	description: "different scheme is cross-site",
+	site:        "http://www.host.test/",
+	method:      "POST",
+	toURL:       "https://www.host.test/",
+	want:        "D=d N=n",
+}}
+
+func TestSameSite(t *testing.T) {
+	jar := newTestJar("")
+	setCookies(jar, "https://www.host.test/", []string{
+		"D=d",
+		"L=l; SameSite=Lax",
+		"N=n; SameSite=None; Secure",
+		"S=s; SameSite=Strict",
+		"X=x; SameSite=None",
+	}, tNow)
+	for _, test := range sameSiteTests {
+		var site *url.URL
+		if test.site != "" {
+			site = mustParseURL(test.site)
+		}
+		var s []string
+		for _, c := range jar.cookiesForRequest(mustParseURL(test.toURL), site, test.method, tNow) {
+			s = append(s, c.Name+"="+c.Value)
+		}
+		sort.Strings(s)
+		if got := strings.Join(s, " "); got != test.want {
+			t.Errorf("Test %q\ngot  %q\nwant %q", test.description, got, test.want)
+		}
+	}
+}
+
+func TestSameSiteSaved(t *testing.T) {
+	c := qt.New(t)
+	d, err := ioutil.TempDir("", "")
+	c.Assert(err, qt.Equals, nil)
+	defer os.RemoveAll(d)
+	file := filepath.Join(d, "cookies")
+	j := newTestJar(file)
+	setCookies(j, "https://www.host.test/", []string{
+		"L=l; SameSite=Lax; max-age=100",
+		"S=s; SameSite=Strict; max-age=100",
+	}, time.Now())
+	err = j.Save()
+	c.Assert(err, qt.Equals, nil)
+	j1 := newTestJar(file)
+	c.Assert(j1.entries, qt.DeepEquals, j.entries)
+	cookies := j1.AllCookies()
+	c.Assert(len(cookies), qt.Equals, 2)
+	c.Assert(cookies[0].SameSite, qt.Equals, http.SameSiteLaxMode)
+	c.Assert(cookies[1].SameSite, qt.Equals, http.SameSiteStrictMode)
+}
+
+var partitionedTests = []struct {
+	description string
+	site        string // top-level site of the request; empty means none
+	toURL       string
+	want        string
+}{{
+	description: "embedded under first site",
+	site:        "https://a.test/",
+	toURL:       "https://widget.test/",
+	want:        "P=a U=u",
+}, {
+	description: "embedded under second site",
+	site:        "https://www.b.test/page",
+	toURL:       "https://widget.test/",
+	want:        "P=b U=u",
+}, {
+	description: "embedded under unrelated site",
+	site:        "https://c.test/",
+	toURL:       "https://widget.test/",
+	want:        "U=u",
+}, {
+	description: "top-level request",
+	toURL:       "https://widget.test/",
+	want:        "P=top U=u",
+}, {
+	description: "partition keys include the scheme",
+	site:        "http://a.test/",
+	toURL:       "https://widget.test/",
+	want:        "U=u",
+}, {
+	description: "cookie set under invalid site is not shared",
+	site:        "https://d.test/",
+	toURL:       "https://widget.test/",
+	want:        "U=u",
+}}
+
+func TestPartitioned(t *testing.T) {
+	jar := newTestJar("")
+	set := func(site string, cookies ...string) {
+		var siteURL *url.URL
+		if site != "" {
+			siteURL = mustParseURL(site)
+		}
+		setCookies := make([]*http.Cookie, len(cookies))
+		for i, cs := range cookies {
+			setCookies[i] = (&http.Response{Header: http.Header{"Set-Cookie": {cs}}}).Cookies()[0]
+		}
+		jar.setCookiesForRequest(mustParseURL("https://widget.test/"), siteURL, setCookies, tNow)
+	}
+	set("https://a.test/", "P=a; Secure; Partitioned", "U=u; Secure")
+	set("https://b.test/", "P=b; Secure; Partitioned", "X=x; Partitioned")
+	set("", "P=top; Secure; Partitioned")
+
+	// A partitioned cookie cannot be stored when the top-level
+	// site has an invalid host, as it has no partition.
+	invalidSite := &url.URL{Scheme: "https", Host: "[::1]:80:"}
+	results := jar.setCookiesForRequest(mustParseURL("https://widget.test/"), invalidSite, []*http.Cookie{{
+		Name:        "Q",
+		Value:       "q",
+		Secure:      true,
+		Partitioned: true,
+	}}, tNow)
+	if rerr, ok := results[0].Err.(*RejectError); results[0].Outcome != OutcomeRejected || !ok || rerr.Rule != RuleHost {
+		t.Errorf("partitioned cookie under invalid site: got outcome %v, error %#v", results[0].Outcome, results[0].Err)
+	}
+
+	for _, test := range partitionedTests {
+		var site *url.URL
+		if test.site != "" {
+			site = mustParseURL(test.site)
+		}
+		var s []string
+		for _, c := range jar.cookiesForRequest(mustParseURL(test.toURL), site, "GET", tNow) {
+			s = append(s, c.Name+"="+c.Value)
+		}
+		sort.Strings(s)
+		if got := strings.Join(s, " "); got != test.want {
+			t.Errorf("Test %q\ngot  %q\nwant %q", test.description, got, test.want)
+		}
+	}
+	if got, want := queryJar(jar, "https://widget.test/", tNow), "P=top U=u"; got != want {
+		t.Errorf("Cookies\ngot  %q\nwant %q", got, want)
+	}
+
+	// Deleting a partitioned cookie only affects its own partition.
+	set("https://a.test/", "P=; Secure; Partitioned; max-age=-1")
+	got := jar.cookiesForRequest(mustParseURL("https://widget.test/"), mustParseURL("https://a.test/"), "GET", tNow)
+	if len(got) != 1 || got[0].Name != "U" {
+		t.Errorf("unexpected cookies after deletion: %v", got)
+	}
+	if got, want := allCookies(jar, tNow), "P=b P=top U=u"; got != want {
+		t.Errorf("Content after deletion\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestPartitionedSaved(t *testing.T) {
+	c := qt.New(t)
+	d, err := ioutil.TempDir("", "")
+	c.Assert(err, qt.Equals, nil)
+	defer os.RemoveAll(d)
+	file := filepath.Join(d, "cookies")
+	j := newTestJar(file)
+	j.SetCookiesForRequest(mustParseURL("https://widget.test/"), mustParseURL("https://a.test/"), []*http.Cookie{{
+		Name:        "P",
+		Value:       "a",
+		Secure:      true,
+		Partitioned: true,
+		MaxAge:      100,
+	}})
+	err = j.Save()
+	c.Assert(err, qt.Equals, nil)
+	j1 := newTestJar(file)
+	c.Assert(j1.entries, qt.DeepEquals, j.entries)
+	cookies := j1.CookiesForRequest(mustParseURL("https://widget.test/"), mustParseURL("https://a.test/"), "GET")
+	c.Assert(len(cookies), qt.Equals, 1)
+	cookies = j1.CookiesForRequest(mustParseURL("https://widget.test/"), mustParseURL("https://b.test/"), "GET")
+	c.Assert(len(cookies), qt.Equals, 0)
+
+	all := j1.AllCookies()
+	c.Assert(len(all), qt.Equals, 1)
+	c.Assert(all[0].Partitioned, qt.Equals, true)
+	j1.RemoveCookie(all[0])
+	c.Assert(len(j1.AllCookies()), qt.Equals, 0)
+}
+
 type mergeCookie struct {
 	when   time.Time
 	url    string
@@ -1477,6 +1860,86 @@ func TestDeleteExpired(t *testing.T) {
 	}
 }
 
+func TestEvictPerDomain(t *testing.T) {
+	jar, err := New(&Options{
+		PublicSuffixList:    testPSL{},
+		NoPersist:           true,
+		MaxCookiesPerDomain: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setCookies(jar, "http://www.host.test", []string{"a=a"}, atTime(0))
+	setCookies(jar, "http://www.host.test", []string{"b=b"}, atTime(1))
+	setCookies(jar, "http://www.host.test/foo/", []string{"c=c"}, atTime(2))
+	setCookies(jar, "http://other.test", []string{"x=x", "y=y", "z=z"}, atTime(3))
+	// Access a and b so that c is the least recently used.
+	queryJar(jar, "http://www.host.test/", atTime(4))
+	setCookies(jar, "http://www.host.test", []string{"d=d"}, atTime(5))
+	if got, want := allCookies(jar, atTime(5)), "a=a b=b d=d x=x y=y z=z"; got != want {
+		t.Errorf("Unexpected content\ngot  %q\nwant %q", got, want)
+	}
+
+	// Recently expired cookies are neither evicted
+	// nor counted towards the limit.
+	setCookies(jar, "http://www.host.test", []string{"a=a; max-age=-1"}, atTime(6))
+	setCookies(jar, "http://www.host.test", []string{"e=e"}, atTime(7))
+	if got, want := allCookiesIncludingExpired(jar, atTime(7)), "a= b=b d=d e=e x=x y=y z=z"; got != want {
+		t.Errorf("Unexpected content\ngot  %q\nwant %q", got, want)
+	}
+
+	// Once they could have been deleted by deleteExpired,
+	// expired cookies are evicted before any others.
+	now := atTime(7).Add(expiryRemovalDuration)
+	setCookies(jar, "http://www.host.test", []string{"f=f"}, now)
+	if got, want := allCookiesIncludingExpired(jar, now), "d=d e=e f=f x=x y=y z=z"; got != want {
+		t.Errorf("Unexpected content\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestEvictTotal(t *testing.T) {
+	jar, err := New(&Options{
+		PublicSuffixList: testPSL{},
+		NoPersist:        true,
+		MaxCookies:       3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	setCookies(jar, "http://a.test", []string{"a=a"}, atTime(0))
+	setCookies(jar, "http://b.test", []string{"b=b"}, atTime(1))
+	setCookies(jar, "http://c.test", []string{"c=c"}, atTime(2))
+	queryJar(jar, "http://a.test", atTime(3))
+	setCookies(jar, "http://d.test", []string{"d=d1", "d=d2; path=/x"}, atTime(4))
+	if got, want := allCookies(jar, atTime(4)), "a=a d=d1 d=d2"; got != want {
+		t.Errorf("Unexpected content\ngot  %q\nwant %q", got, want)
+	}
+	if len(jar.entries) != 2 {
+		t.Errorf("empty submaps not deleted: %v", jar.entries)
+	}
+}
+
+func TestEvictOnLoad(t *testing.T) {
+	c := qt.New(t)
+	d, err := ioutil.TempDir("", "")
+	c.Assert(err, qt.Equals, nil)
+	defer os.RemoveAll(d)
+	file := filepath.Join(d, "cookies")
+	j := newTestJar(file)
+	setCookies(j, "http://www.host.test", []string{"a=a; max-age=100"}, time.Now())
+	setCookies(j, "http://www.host.test", []string{"b=b; max-age=100"}, time.Now().Add(time.Second))
+	err = j.Save()
+	c.Assert(err, qt.Equals, nil)
+
+	j1, err := New(&Options{
+		PublicSuffixList:    testPSL{},
+		Filename:            file,
+		MaxCookiesPerDomain: 1,
+	})
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(allCookies(j1, time.Now()), qt.Equals, "b=b")
+}
+
 var serializeTestCookies = []*http.Cookie{{
 	Name:       "foo",
 	Value:      "bar",
@@ -0,0 +1,293 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/errgo.v1"
+)
+
+// lwpMagic is the start of the first line of an LWP cookies file.
+const lwpMagic = "#LWP-Cookies-"
+
+// lwpLinePrefix is the prefix of each cookie line
+// in an LWP cookies file.
+const lwpLinePrefix = "Set-Cookie3:"
+
+// lwpTimeFormat is the format of the expires attribute
+// in an LWP cookies file.
+const lwpTimeFormat = "2006-01-02 15:04:05Z"
+
+// WriteLWP writes all the unexpired cookies in the jar to w in the
+// "Set-Cookie3" format used by libwww-perl and by Python's
+// http.cookiejar.LWPCookieJar.
+//
+// Session cookies are written with the discard attribute, so
+// LWPCookieJar only loads them if its ignore_discard argument is true.
+// Partitioned cookies cannot be represented in the format and are
+// omitted.
+func (j *Jar) WriteLWP(w io.Writer) error {
+	return j.writeLWP(w, time.Now())
+}
+
+// writeLWP is like WriteLWP but takes the current time as a parameter.
+func (j *Jar) writeLWP(w io.Writer, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(lwpMagic + "2.0\n")
+	for _, e := range j.allEntries(false, now) {
+		if e.PartitionKey != "" {
+			continue
+		}
+		domain := e.Domain
+		if !e.HostOnly {
+			domain = "." + domain
+		}
+		// The attributes are written in the same order
+		// as LWPCookieJar writes them.
+		attrs := []string{
+			lwpAttr(e.Name, e.Value),
+			lwpAttr("path", e.Path),
+			lwpAttr("domain", domain),
+			"path_spec",
+		}
+		if !e.HostOnly {
+			attrs = append(attrs, "domain_dot")
+		}
+		if e.Secure {
+			attrs = append(attrs, "secure")
+		}
+		if e.Persistent {
+			attrs = append(attrs, lwpAttr("expires", e.Expires.UTC().Format(lwpTimeFormat)))
+		} else {
+			attrs = append(attrs, "discard")
+		}
+		if e.HttpOnly {
+			attrs = append(attrs, "HttpOnly")
+		}
+		if e.SameSite != "" {
+			attrs = append(attrs, lwpAttr("SameSite", e.SameSite))
+		}
+		attrs = append(attrs, "version=0")
+		bw.WriteString(lwpLinePrefix + " " + strings.Join(attrs, "; ") + "\n")
+	}
+	return errgo.Mask(bw.Flush())
+}
+
+// lwpAttr returns the given attribute formatted as
+// Python's http.cookiejar.join_header_words does.
+func lwpAttr(name, value string) string {
+	if value != "" && strings.IndexFunc(value, func(r rune) bool {
+		return !isLWPWordChar(r)
+	}) == -1 {
+		return name + "=" + value
+	}
+	var buf bytes.Buffer
+	buf.WriteString(name)
+	buf.WriteString(`="`)
+	for _, r := range value {
+		if r == '"' || r == '\\' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteRune(r)
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+func isLWPWordChar(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_'
+}
+
+// ReadLWP reads cookies in the "Set-Cookie3" format used by
+// libwww-perl and by Python's http.cookiejar.LWPCookieJar from r and
+// stores them in the jar. Cookies read from r replace cookies with the
+// same name, domain and path already in the jar, regardless of when
+// those were last updated.
+//
+// A cookie is a domain cookie if its domain has a leading dot or it
+// has the domain_dot attribute, and a session cookie if it has the
+// discard attribute or no expires attribute. The port restrictions of
+// RFC 2965 cookies are ignored, as they are for all cookies by RFC
+// 6265.
+//
+// Unlike LWPCookieJar, session cookies are always read. Cookies for
+// domains that the jar's public suffix list does not allow cookies to
+// be set for are ignored. An error is returned if the data does not
+// start with an LWP header or any line is malformed, in which
+// case the jar is not changed.
+func (j *Jar) ReadLWP(r io.Reader) error {
+	return j.readLWP(r, time.Now())
+}
+
+// readLWP is like ReadLWP but takes the current time as a parameter.
+func (j *Jar) readLWP(r io.Reader, now time.Time) error {
+	var entries []entry
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() || !strings.HasPrefix(scanner.Text(), lwpMagic) {
+		if err := scanner.Err(); err != nil {
+			return errgo.Mask(err)
+		}
+		return errgo.New("not an LWP cookies file")
+	}
+	for lineNum := 2; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, lwpLinePrefix) {
+			continue
+		}
+		e, ok, err := j.parseLWPLine(line[len(lwpLinePrefix):], now)
+		if err != nil {
+			return errgo.Notef(err, "line %d", lineNum)
+		}
+		if ok {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errgo.Mask(err)
+	}
+	defer j.notify()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.replaceEntries(entries, now)
+	j.evict(now)
+	return nil
+}
+
+// parseLWPLine parses the attributes of a single Set-Cookie3 line. It
+// returns false if the line holds a cookie that should be ignored.
+func (j *Jar) parseLWPLine(line string, now time.Time) (entry, bool, error) {
+	var e entry
+	attrs := splitLWPAttrs(line)
+	if len(attrs) == 0 {
+		return e, false, errgo.New("no cookie found")
+	}
+	e.Name, e.Value = attrs[0].name, attrs[0].value
+	var domain, expires string
+	var domainDot, discard bool
+	for _, attr := range attrs[1:] {
+		switch strings.ToLower(attr.name) {
+		case "domain":
+			domain = attr.value
+		case "domain_dot":
+			domainDot = true
+		case "path":
+			e.Path = attr.value
+		case "secure":
+			e.Secure = true
+		case "expires":
+			expires = attr.value
+		case "discard":
+			discard = true
+		case "httponly":
+			// LWPCookieJar stores attributes that it does not
+			// know about with their values, so HttpOnly is
+			// written as "HttpOnly=None".
+			e.HttpOnly = true
+		case "samesite":
+			switch v := strings.ToLower(attr.value); v {
+			case "strict", "lax", "none":
+				e.SameSite = strings.ToUpper(v[:1]) + v[1:]
+			}
+		}
+		// The path_spec, port and port_spec attributes
+		// have no equivalent in RFC 6265.
+	}
+	if domain == "" {
+		return e, false, errgo.Newf("cookie %q has no domain", e.Name)
+	}
+	if err := j.setImportedDomain(&e, domain, !strings.HasPrefix(domain, ".") && !domainDot); err != nil {
+		if _, ok := err.(*RejectError); ok {
+			// The jar would not accept the cookie from
+			// a server, so don't accept it here either.
+			return e, false, nil
+		}
+		return e, false, errgo.Mask(err)
+	}
+	if e.Path == "" || e.Path[0] != '/' {
+		e.Path = "/"
+	}
+	e.Expires = endOfTime
+	if expires != "" {
+		t, err := time.Parse(lwpTimeFormat, expires)
+		if err != nil {
+			return e, false, errgo.Newf("invalid expiry time %q", expires)
+		}
+		if !t.After(now) {
+			return e, false, nil
+		}
+		e.Expires = t
+		e.Persistent = !discard
+	}
+	e.Creation = now
+	e.LastAccess = now
+	e.Updated = now
+	return e, true, nil
+}
+
+// lwpAttrValue holds an attribute in a Set-Cookie3 line. The value
+// of an attribute without one, such as "secure", is empty.
+type lwpAttrValue struct {
+	name, value string
+}
+
+// splitLWPAttrs splits a Set-Cookie3 line into its attributes, as
+// Python's http.cookiejar.split_header_words does.
+func splitLWPAttrs(s string) []lwpAttrValue {
+	var attrs []lwpAttrValue
+	for {
+		s = strings.TrimLeft(s, " \t;=")
+		if s == "" || s[0] == ',' {
+			// A comma separates the cookies in a header
+			// holding several, which never happens in
+			// LWP files.
+			return attrs
+		}
+		i := strings.IndexAny(s, "= \t;,")
+		if i < 0 {
+			i = len(s)
+		}
+		attr := lwpAttrValue{name: s[:i]}
+		s = strings.TrimLeft(s[i:], " \t")
+		if strings.HasPrefix(s, "=") {
+			s = strings.TrimLeft(s[1:], " \t")
+			if strings.HasPrefix(s, `"`) {
+				attr.value, s = unquoteLWPValue(s[1:])
+			} else {
+				i := strings.IndexAny(s, " \t;,")
+				if i < 0 {
+					i = len(s)
+				}
+				attr.value, s = s[:i], s[i:]
+			}
+		}
+		attrs = append(attrs, attr)
+	}
+}
+
+// unquoteLWPValue returns the quoted value at the start of s, which
+// follows an opening quote, and the rest of s after the closing
+// quote.
+func unquoteLWPValue(s string) (string, string) {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				buf.WriteByte(s[i])
+			}
+		case '"':
+			return buf.String(), s[i+1:]
+		default:
+			buf.WriteByte(s[i])
+		}
+	}
+	return buf.String(), ""
+}
@@ -0,0 +1,122 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestWriteLWP(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	setCookies(jar, "https://www.host.test/foo/", []string{
+		"host=1",
+		"domain=a,b; domain=host.test; path=/; samesite=lax; " + expiresIn(100),
+		"secure=3; secure; httponly; max-age=200",
+		"expired=4; max-age=1",
+	}, tNow)
+	jar.setCookiesForRequest(mustParseURL("https://embed.test/"), mustParseURL("https://site.test/"), []*http.Cookie{{Name: "partitioned", Value: "5", Secure: true, Partitioned: true}}, tNow)
+
+	var buf bytes.Buffer
+	err := jar.writeLWP(&buf, atTime(10))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(buf.String(), qt.Equals, "#LWP-Cookies-2.0\n"+
+		`Set-Cookie3: host=1; path="/foo"; domain="www.host.test"; path_spec; discard; version=0`+"\n"+
+		`Set-Cookie3: secure=3; path="/foo"; domain="www.host.test"; path_spec; secure; expires="2013-01-01 12:03:20Z"; HttpOnly; version=0`+"\n"+
+		`Set-Cookie3: domain="a,b"; path="/"; domain=".host.test"; path_spec; domain_dot; expires="2013-01-01 12:01:40Z"; SameSite=Lax; version=0`+"\n",
+	)
+}
+
+func TestLWPRoundTrip(t *testing.T) {
+	c := qt.New(t)
+	j0 := newTestJar("")
+	setCookies(j0, "https://www.host.test/foo/", []string{
+		"host=1",
+		"domain=a,b; domain=host.test; path=/; samesite=strict; " + expiresIn(100),
+		`secure="x y"; secure; httponly; max-age=200`,
+	}, tNow)
+	checkRoundTrip(c, j0, (*Jar).writeLWP, (*Jar).readLWP)
+}
+
+// pythonLWPCookies holds cookies as written by Python's LWPCookieJar,
+// with some additions.
+const pythonLWPCookies = `#LWP-Cookies-2.0
+Set-Cookie3: a=1; path="/"; domain="www.host.test"; path_spec; discard; HttpOnly=None; version=0
+Set-Cookie3: b=2; path="/x"; domain=".host.test"; path_spec; domain_dot; secure; expires="2013-01-01 12:01:40Z"; SameSite=Lax; version=0
+Set-Cookie3: c=""; path="/"; domain="other.test"; domain_dot; expires="2013-01-01 12:01:40Z"; discard; version=0
+Set-Cookie3: d=4; path="/"; domain="www.host.test"; port="80,8080"; port_spec; path_spec; version=1
+Set-Cookie3: expired=5; path="/"; domain="www.host.test"; expires="2013-01-01 12:00:00Z"; version=0
+Set-Cookie3: publicsuffix=6; path="/"; domain=".co.uk"; version=0
+Set-Cookie3: quoted="say \"hi\""; path="/"; domain="www.host.test"; version=0
+# A comment.
+`
+
+func TestReadLWP(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	setCookies(jar, "http://www.host.test/", []string{"keep=1"}, tNow)
+	// Cookies read replace those in the jar even when the
+	// jar's cookie was updated more recently.
+	setCookies(jar, "http://www.host.test/", []string{"a=old; max-age=1000"}, atTime(100))
+	err := jar.readLWP(strings.NewReader(pythonLWPCookies), atTime(1))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(allCookies(jar, atTime(1)), qt.Equals, "a=1 b=2 c= d=4 keep=1 publicsuffix=6 quoted=say \"hi\"")
+	c.Assert(queryJar(jar, "https://sub.host.test/x/y", atTime(1)), qt.Equals, "b=2")
+	c.Assert(queryJar(jar, "http://www.other.test/", atTime(1)), qt.Equals, "c=")
+
+	entries := make(map[string]Entry)
+	for _, e := range jar.allEntries(false, atTime(1)) {
+		entries[e.Name] = e
+	}
+	c.Assert(entries["a"].HttpOnly, qt.Equals, true)
+	c.Assert(entries["a"].Persistent, qt.Equals, false)
+	c.Assert(entries["b"].HttpOnly, qt.Equals, false)
+	c.Assert(entries["b"].SameSite, qt.Equals, "Lax")
+	c.Assert(entries["b"].Persistent, qt.Equals, true)
+	c.Assert(entries["b"].Expires, qt.DeepEquals, tNow.Add(100e9))
+	// A cookie with an expiry time that is to be
+	// discarded is a session cookie that expires.
+	c.Assert(entries["c"].Persistent, qt.Equals, false)
+	c.Assert(entries["c"].Expires, qt.DeepEquals, tNow.Add(100e9))
+	c.Assert(entries["d"].Expires, qt.Equals, endOfTime)
+}
+
+var readLWPErrorTests = []struct {
+	data   string
+	expect string
+}{{
+	data:   "",
+	expect: "not an LWP cookies file",
+}, {
+	data:   "# Netscape HTTP Cookie File\n",
+	expect: "not an LWP cookies file",
+}, {
+	data:   "#LWP-Cookies-2.0\nSet-Cookie3: ; ;\n",
+	expect: "line 2: no cookie found",
+}, {
+	data:   "#LWP-Cookies-2.0\nSet-Cookie3: a=1; path=\"/\"\n",
+	expect: `line 2: cookie "a" has no domain`,
+}, {
+	data:   "#LWP-Cookies-2.0\nSet-Cookie3: a=1; domain=\".\"\n",
+	expect: `line 2: invalid domain "."`,
+}, {
+	data:   "#LWP-Cookies-2.0\nSet-Cookie3: a=1; domain=\"host.test\"; expires=never\n",
+	expect: `line 2: invalid expiry time "never"`,
+}}
+
+func TestReadLWPError(t *testing.T) {
+	c := qt.New(t)
+	for _, test := range readLWPErrorTests {
+		jar := newTestJar("")
+		err := jar.readLWP(strings.NewReader(test.data), tNow)
+		c.Assert(err, qt.ErrorMatches, test.expect)
+		c.Assert(allCookies(jar, tNow), qt.Equals, "")
+	}
+}
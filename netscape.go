@@ -0,0 +1,187 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/errgo.v1"
+)
+
+// httpOnlyPrefix is the prefix that curl and others add to the
+// domain field of a cookies.txt line to mark an HttpOnly cookie.
+const httpOnlyPrefix = "#HttpOnly_"
+
+// netscapeHeader is written at the start of a cookies.txt file.
+// Some tools refuse to read files that do not start with it.
+const netscapeHeader = `# Netscape HTTP Cookie File
+# https://curl.se/docs/http-cookies.html
+# This file was generated by persistent-cookiejar. Edit at your own risk.
+
+`
+
+// WriteNetscape writes all the unexpired cookies in the jar to w in
+// the Netscape cookies.txt format used by curl, wget and other tools.
+// Session cookies are written with an expiry time of zero. Partitioned
+// cookies cannot be represented in the format and are omitted.
+func (j *Jar) WriteNetscape(w io.Writer) error {
+	return j.writeNetscape(w, time.Now())
+}
+
+// writeNetscape is like WriteNetscape but takes the current time as a
+// parameter.
+func (j *Jar) writeNetscape(w io.Writer, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(netscapeHeader)
+	for _, e := range j.allEntries(false, now) {
+		if e.PartitionKey != "" {
+			continue
+		}
+		domain := e.Domain
+		if !e.HostOnly {
+			domain = "." + domain
+		}
+		if e.HttpOnly {
+			domain = httpOnlyPrefix + domain
+		}
+		var expires int64
+		if e.Persistent {
+			expires = e.Expires.Unix()
+		}
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			domain,
+			netscapeBool(!e.HostOnly),
+			e.Path,
+			netscapeBool(e.Secure),
+			expires,
+			e.Name,
+			e.Value,
+		)
+	}
+	return errgo.Mask(bw.Flush())
+}
+
+func netscapeBool(b bool) string {
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+// ReadNetscape reads cookies in the Netscape cookies.txt format from r
+// and stores them in the jar. Cookies read from r replace cookies with
+// the same name, domain and path already in the jar, regardless of
+// when those were last updated.
+// Cookies with an expiry time of zero are treated as session cookies.
+//
+// Cookies for domains that the jar's public suffix list does not
+// allow cookies to be set for are ignored. An error is returned if
+// any line is malformed, in which case the jar is not changed.
+func (j *Jar) ReadNetscape(r io.Reader) error {
+	return j.readNetscape(r, time.Now())
+}
+
+// readNetscape is like ReadNetscape but takes the current time as a
+// parameter.
+func (j *Jar) readNetscape(r io.Reader, now time.Time) error {
+	var entries []entry
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		e, ok, err := j.parseNetscapeLine(scanner.Text(), now)
+		if err != nil {
+			return errgo.Notef(err, "line %d", lineNum)
+		}
+		if ok {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errgo.Mask(err)
+	}
+	defer j.notify()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.replaceEntries(entries, now)
+	j.evict(now)
+	return nil
+}
+
+// parseNetscapeLine parses a single line of a cookies.txt file. It
+// returns false if the line holds no cookie or holds a cookie that
+// should be ignored.
+func (j *Jar) parseNetscapeLine(line string, now time.Time) (entry, bool, error) {
+	line = strings.TrimRight(line, "\r")
+	var e entry
+	if strings.HasPrefix(line, httpOnlyPrefix) {
+		e.HttpOnly = true
+		line = line[len(httpOnlyPrefix):]
+	} else if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+		return e, false, nil
+	}
+	fields := strings.Split(line, "\t")
+	switch len(fields) {
+	case 7:
+	case 6:
+		// Some tools omit the field altogether
+		// when the value is empty.
+		fields = append(fields, "")
+	default:
+		return e, false, errgo.Newf("expected 7 tab-separated fields, found %d", len(fields))
+	}
+	includeSubdomains, err := parseNetscapeBool(fields[1])
+	if err != nil {
+		return e, false, errgo.Mask(err)
+	}
+	e.Secure, err = parseNetscapeBool(fields[3])
+	if err != nil {
+		return e, false, errgo.Mask(err)
+	}
+	expires, err := strconv.ParseInt(fields[4], 10, 64)
+	if err != nil {
+		return e, false, errgo.Newf("invalid expiry time %q", fields[4])
+	}
+	if err := j.setImportedDomain(&e, fields[0], !includeSubdomains); err != nil {
+		if _, ok := err.(*RejectError); ok {
+			// The jar would not accept the cookie from
+			// a server, so don't accept it here either.
+			return e, false, nil
+		}
+		return e, false, errgo.Mask(err)
+	}
+	e.Path = fields[2]
+	if e.Path == "" || e.Path[0] != '/' {
+		e.Path = "/"
+	}
+	if expires == 0 {
+		e.Expires = endOfTime
+	} else {
+		e.Persistent = true
+		e.Expires = time.Unix(expires, 0).UTC()
+		if !e.Expires.After(now) {
+			return e, false, nil
+		}
+	}
+	e.Name = fields[5]
+	e.Value = fields[6]
+	e.Creation = now
+	e.LastAccess = now
+	e.Updated = now
+	return e, true, nil
+}
+
+func parseNetscapeBool(s string) (bool, error) {
+	switch s {
+	case "TRUE":
+		return true, nil
+	case "FALSE":
+		return false, nil
+	}
+	return false, errgo.Newf("invalid boolean %q", s)
+}
@@ -0,0 +1,143 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestWriteNetscape(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	setCookies(jar, "https://www.host.test/foo/", []string{
+		"host=1",
+		"domain=2; domain=host.test; path=/; " + expiresIn(100),
+		"secure=3; secure; httponly; max-age=200",
+		"expired=4; max-age=1",
+	}, tNow)
+	jar.setCookiesForRequest(mustParseURL("https://embed.test/"), mustParseURL("https://site.test/"), []*http.Cookie{{Name: "partitioned", Value: "5", Secure: true, Partitioned: true}}, tNow)
+
+	var buf bytes.Buffer
+	err := jar.writeNetscape(&buf, atTime(10))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(buf.String(), qt.Equals, netscapeHeader+
+		"www.host.test\tFALSE\t/foo\tFALSE\t0\thost\t1\n"+
+		"#HttpOnly_www.host.test\tFALSE\t/foo\tTRUE\t1357041800\tsecure\t3\n"+
+		".host.test\tTRUE\t/\tFALSE\t1357041700\tdomain\t2\n",
+	)
+}
+
+func TestNetscapeRoundTrip(t *testing.T) {
+	c := qt.New(t)
+	j0 := newTestJar("")
+	setCookies(j0, "https://www.host.test/foo/", []string{
+		"host=1",
+		"domain=2; domain=host.test; path=/; " + expiresIn(100),
+		"secure=3; secure; httponly; max-age=200",
+	}, tNow)
+	checkRoundTrip(c, j0, (*Jar).writeNetscape, (*Jar).readNetscape)
+}
+
+// checkRoundTrip checks that the cookies in j0 are unchanged when
+// they are written with write and read into a new jar with read,
+// and returns the new jar.
+func checkRoundTrip(c *qt.C, j0 *Jar, write func(*Jar, io.Writer, time.Time) error, read func(*Jar, io.Reader, time.Time) error) *Jar {
+	var buf bytes.Buffer
+	err := write(j0, &buf, tNow)
+	c.Assert(err, qt.Equals, nil)
+
+	j1 := newTestJar("")
+	err = read(j1, &buf, tNow)
+	c.Assert(err, qt.Equals, nil)
+	// The host that set a domain cookie is not recorded in the
+	// written data, so the cookie's domain is used instead.
+	want := j0.allEntries(true, tNow)
+	for i := range want {
+		want[i].CanonicalHost = want[i].Domain
+	}
+	got := j1.allEntries(true, tNow)
+	sort.Slice(got, func(i, j int) bool { return got[i].Name < got[j].Name })
+	sort.Slice(want, func(i, j int) bool { return want[i].Name < want[j].Name })
+	c.Assert(got, qt.DeepEquals, want)
+	c.Assert(j1.dirty, qt.Equals, true)
+	return j1
+}
+
+const curlCookies = "# Netscape HTTP Cookie File\n" +
+	"# https://curl.se/docs/http-cookies.html\n" +
+	"\n" +
+	"www.host.test\tFALSE\t/\tFALSE\t0\ta\t1\r\n" +
+	".host.test\tTRUE\t/x\tTRUE\t1357041700\tb\t2\n" +
+	"#HttpOnly_other.test\tTRUE\t/\tFALSE\t0\tc\t3\n" +
+	"www.host.test\tFALSE\t/\tFALSE\t0\tempty\n" +
+	"www.host.test\tFALSE\t/\tFALSE\t1\texpired\t4\n" +
+	".co.uk\tTRUE\t/\tFALSE\t0\tpublicsuffix\t5\n" +
+	"co.uk\tFALSE\t/\tFALSE\t0\tpublicsuffixhost\t6\n"
+
+func TestReadNetscape(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	setCookies(jar, "http://www.host.test/", []string{"a=old", "keep=1"}, tNow)
+	err := jar.readNetscape(strings.NewReader(curlCookies), atTime(1))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(allCookies(jar, atTime(1)), qt.Equals, "a=1 b=2 c=3 empty= keep=1 publicsuffix=5 publicsuffixhost=6")
+	c.Assert(queryJar(jar, "https://sub.host.test/x/y", atTime(1)), qt.Equals, "b=2")
+	c.Assert(queryJar(jar, "http://www.other.test/", atTime(1)), qt.Equals, "c=3")
+
+	// As with cookies set by a server, a domain cookie for a
+	// public suffix is treated as a host cookie.
+	c.Assert(queryJar(jar, "http://www.host.co.uk/", atTime(1)), qt.Equals, "")
+
+	for _, cookie := range jar.allCookies(atTime(1)) {
+		c.Assert(cookie.HttpOnly, qt.Equals, cookie.Name == "c")
+	}
+}
+
+func TestReadNetscapeReplaces(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	// The existing cookie was updated after the
+	// time that the file is read, as can happen when
+	// it is merged from a machine whose clock is ahead.
+	setCookies(jar, "http://www.host.test/", []string{"a=newer; max-age=1000"}, atTime(100))
+	err := jar.readNetscape(strings.NewReader("www.host.test\tFALSE\t/\tFALSE\t0\ta\tfromfile\n"), atTime(1))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(queryJar(jar, "http://www.host.test/", atTime(1)), qt.Equals, "a=fromfile")
+}
+
+var readNetscapeErrorTests = []struct {
+	line   string
+	expect string
+}{{
+	line:   "www.host.test\tFALSE\t/\tFALSE\t0",
+	expect: "line 2: expected 7 tab-separated fields, found 5",
+}, {
+	line:   "www.host.test\tyes\t/\tFALSE\t0\ta\t1",
+	expect: `line 2: invalid boolean "yes"`,
+}, {
+	line:   "www.host.test\tFALSE\t/\tFALSE\tnever\ta\t1",
+	expect: `line 2: invalid expiry time "never"`,
+}, {
+	line:   ".\tTRUE\t/\tFALSE\t0\ta\t1",
+	expect: `line 2: invalid domain "."`,
+}}
+
+func TestReadNetscapeError(t *testing.T) {
+	c := qt.New(t)
+	for _, test := range readNetscapeErrorTests {
+		jar := newTestJar("")
+		err := jar.readNetscape(strings.NewReader("www.host.test\tFALSE\t/\tFALSE\t0\tok\t1\n"+test.line+"\n"), tNow)
+		c.Assert(err, qt.ErrorMatches, test.expect)
+		c.Assert(allCookies(jar, tNow), qt.Equals, "")
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Overlay is a cookie jar that reads through to a parent jar but
+// keeps its own changes separate from it. Cookies that are set in
+// the overlay hide those in the parent, and cookies that are removed
+// from the overlay are hidden without being removed from the parent.
+// The parent is never changed except by Commit.
+//
+// Overlay implements the http.CookieJar interface. Like Snapshot,
+// it does not record the time that cookies were accessed.
+type Overlay struct {
+	parent *Jar
+
+	// child holds the changes made in the overlay,
+	// including tombstones for removed cookies.
+	child *Jar
+}
+
+// NewOverlay returns a new overlay on top of the given parent jar.
+// Cookies set in the overlay are subject to the same public suffix
+// list and policy as cookies set in the parent.
+func NewOverlay(parent *Jar) *Overlay {
+	return &Overlay{
+		parent: parent,
+		child:  parent.newChild(),
+	}
+}
+
+// newChild returns an empty in-memory jar that
+// applies the same rules as j.
+func (j *Jar) newChild() *Jar {
+	return &Jar{
+		psList:             j.psList,
+		policy:             j.policy,
+		maxSessionLifetime: j.maxSessionLifetime,
+		entries:            make(map[string]map[string]entry),
+		parent:             j,
+	}
+}
+
+// lookup returns the entry with the given jar key and id.
+// It is called by an overlay's child jar with the child's lock
+// held, so a parent jar must never acquire the lock of a child.
+func (j *Jar) lookup(key, id string) (entry, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	e, ok := j.entries[key][id]
+	return e, ok
+}
+
+// Cookies implements the Cookies method of the http.CookieJar interface.
+func (o *Overlay) Cookies(u *url.URL) []*http.Cookie {
+	return o.cookies(u, time.Now())
+}
+
+// cookies is like Cookies but takes the current time as a parameter.
+func (o *Overlay) cookies(u *url.URL, now time.Time) []*http.Cookie {
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return nil
+	}
+	key := jarKey(host, o.parent.psList)
+	return matchingCookies(o.view(key), o.parent.psList, u, now)
+}
+
+// AllCookies is like Jar.AllCookies but returns the cookies
+// visible through the overlay.
+func (o *Overlay) AllCookies() []*http.Cookie {
+	return liveCookies(o.view(""), time.Now())
+}
+
+// SetCookies implements the SetCookies method of the http.CookieJar
+// interface. The cookies are stored in the overlay only.
+func (o *Overlay) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	o.child.SetCookies(u, cookies)
+}
+
+// RemoveCookie is like Jar.RemoveCookie but removes the cookie
+// from the overlay only.
+func (o *Overlay) RemoveCookie(c *http.Cookie) {
+	o.remove(jarKey(c.Domain, o.parent.psList), func(e *entry) bool {
+		return e.Name == c.Name && e.Domain == c.Domain && e.Path == c.Path && (e.PartitionKey != "") == c.Partitioned
+	})
+}
+
+// RemoveAllHost is like Jar.RemoveAllHost but removes the cookies
+// from the overlay only.
+func (o *Overlay) RemoveAllHost(host string) {
+	host, err := canonicalHost(host)
+	if err != nil {
+		return
+	}
+	o.remove(jarKey(host, o.parent.psList), func(e *entry) bool {
+		return e.CanonicalHost == host
+	})
+}
+
+// RemoveAll is like Jar.RemoveAll but removes the cookies
+// from the overlay only.
+func (o *Overlay) RemoveAll() {
+	o.remove("", func(e *entry) bool {
+		return true
+	})
+}
+
+// Commit applies the changes made in the overlay to the parent jar,
+// replacing the parent's versions of any cookies that were set or
+// removed in the overlay, and then discards them from the overlay.
+func (o *Overlay) Commit() {
+	o.child.mu.Lock()
+	entries := allEntries(o.child.entries, true, time.Now())
+	o.child.entries = make(map[string]map[string]entry)
+	o.child.mu.Unlock()
+	// The entries all have a canonical host, so this cannot fail.
+	o.parent.SetEntries(entries)
+}
+
+// Discard discards all the changes made in the overlay.
+func (o *Overlay) Discard() {
+	o.child.mu.Lock()
+	defer o.child.mu.Unlock()
+	o.child.entries = make(map[string]map[string]entry)
+}
+
+// view returns the entries visible through the overlay under the
+// given jar key, or under all keys if key is empty.
+func (o *Overlay) view(key string) map[string]map[string]entry {
+	entries := make(map[string]map[string]entry)
+	add := func(j *Jar) {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		for k, submap := range j.entries {
+			if key != "" && k != key {
+				continue
+			}
+			m := entries[k]
+			if m == nil {
+				m = make(map[string]entry)
+				entries[k] = m
+			}
+			for id, e := range submap {
+				m[id] = e
+			}
+		}
+	}
+	add(o.parent)
+	add(o.child)
+	return entries
+}
+
+// remove marks the entries under the given jar key (or all keys if
+// key is empty) that match the given function as removed in the
+// overlay, including matching entries that are only in the parent.
+func (o *Overlay) remove(key string, match func(e *entry) bool) {
+	now := time.Now()
+	var inherited []entry
+	o.parent.mu.Lock()
+	for k, submap := range o.parent.entries {
+		if key != "" && k != key {
+			continue
+		}
+		for _, e := range submap {
+			if e.Expires.After(now) && match(&e) {
+				inherited = append(inherited, e)
+			}
+		}
+	}
+	o.parent.mu.Unlock()
+
+	j := o.child
+	defer j.notify()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, e := range inherited {
+		k := jarKey(e.CanonicalHost, j.psList)
+		submap := j.entries[k]
+		if submap == nil {
+			submap = make(map[string]entry)
+			j.entries[k] = submap
+		}
+		id := e.id()
+		if _, ok := submap[id]; !ok {
+			// The overlay does not have its own version
+			// of the cookie, so take a copy of the parent's
+			// to be removed below.
+			submap[id] = e
+		}
+	}
+	for k, submap := range j.entries {
+		if key != "" && k != key {
+			continue
+		}
+		for id, e := range submap {
+			if match(&e) {
+				j.remove(submap, id, now)
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// queryOverlay returns the cookies that o would send to toURL
+// in the form "name1=val1 name2=val2".
+func queryOverlay(o *Overlay, toURL string) string {
+	var s []string
+	for _, c := range o.Cookies(mustParseURL(toURL)) {
+		s = append(s, c.Name+"="+c.Value)
+	}
+	return strings.Join(s, " ")
+}
+
+func newOverlayTestJar() *Jar {
+	jar := newTestJar("")
+	now := time.Now()
+	setCookies(jar, "http://www.host.test/", []string{"a=1", "b=1", "c=1; domain=host.test"}, now)
+	setCookies(jar, "http://other.test/", []string{"d=1"}, now)
+	return jar
+}
+
+func TestOverlay(t *testing.T) {
+	c := qt.New(t)
+	parent := newOverlayTestJar()
+	before := allCookies(parent, time.Now())
+	o := NewOverlay(parent)
+	c.Assert(queryOverlay(o, "http://www.host.test/"), qt.Equals, "a=1 b=1 c=1")
+
+	o.SetCookies(mustParseURL("http://www.host.test/"), []*http.Cookie{{Name: "a", Value: "2"}, {Name: "e", Value: "1"}})
+	o.RemoveCookie(&http.Cookie{Name: "b", Domain: "www.host.test", Path: "/"})
+	o.RemoveAllHost("other.test")
+	c.Assert(queryOverlay(o, "http://www.host.test/"), qt.Equals, "a=2 c=1 e=1")
+	c.Assert(queryOverlay(o, "http://other.test/"), qt.Equals, "")
+	c.Assert(len(o.AllCookies()), qt.Equals, 3)
+
+	// The parent is unchanged.
+	c.Assert(allCookies(parent, time.Now()), qt.Equals, before)
+
+	// Changes to the parent show through unless
+	// the overlay has its own version.
+	setCookies(parent, "http://www.host.test/", []string{"a=3", "f=1"}, time.Now())
+	c.Assert(queryOverlay(o, "http://www.host.test/"), qt.Equals, "a=2 c=1 e=1 f=1")
+}
+
+func TestOverlayCommit(t *testing.T) {
+	c := qt.New(t)
+	parent := newOverlayTestJar()
+	var r eventRecorder
+	parent.Subscribe(r.record)
+	o := NewOverlay(parent)
+	o.SetCookies(mustParseURL("http://www.host.test/"), []*http.Cookie{{Name: "a", Value: "2"}, {Name: "e", Value: "1"}})
+	o.RemoveCookie(&http.Cookie{Name: "c", Domain: "host.test", Path: "/"})
+	c.Assert(r.take(), qt.Equals, "")
+
+	o.Commit()
+	c.Assert(allCookies(parent, time.Now()), qt.Equals, "a=2 b=1 d=1 e=1")
+	c.Assert(allCookiesIncludingExpired(parent, time.Now()), qt.Equals, "a=2 b=1 c= d=1 e=1")
+	c.Assert(len(r.events), qt.Equals, 3)
+
+	// The overlay is empty after committing.
+	c.Assert(len(o.child.entries), qt.Equals, 0)
+	c.Assert(queryOverlay(o, "http://www.host.test/"), qt.Equals, "a=2 b=1 e=1")
+}
+
+func TestOverlayDiscard(t *testing.T) {
+	c := qt.New(t)
+	parent := newOverlayTestJar()
+	o := NewOverlay(parent)
+	o.SetCookies(mustParseURL("http://www.host.test/"), []*http.Cookie{{Name: "a", Value: "2"}})
+	o.RemoveAll()
+	c.Assert(len(o.AllCookies()), qt.Equals, 0)
+
+	o.Discard()
+	c.Assert(queryOverlay(o, "http://www.host.test/"), qt.Equals, "a=1 b=1 c=1")
+	c.Assert(len(o.AllCookies()), qt.Equals, 4)
+	o.Commit()
+	c.Assert(allCookies(parent, time.Now()), qt.Equals, "a=1 b=1 c=1 d=1")
+}
@@ -0,0 +1,40 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"crypto/hmac"
+	"hash"
+)
+
+// pbkdf2Key derives a key of the given length from password and salt
+// using PBKDF2 (RFC 8018 section 5.2) with HMAC based on the given
+// hash function as the pseudorandom function.
+func pbkdf2Key(h func() hash.Hash, password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(h, password)
+	hashLen := prf.Size()
+	var key []byte
+	var block [4]byte
+	u := make([]byte, 0, hashLen)
+	t := make([]byte, hashLen)
+	for i := 1; len(key) < keyLen; i++ {
+		block[0], block[1], block[2], block[3] = byte(i>>24), byte(i>>16), byte(i>>8), byte(i)
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(block[:])
+		u = prf.Sum(u[:0])
+		copy(t, u)
+		for n := 1; n < iter; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for k := range t {
+				t[k] ^= u[k]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
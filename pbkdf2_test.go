@@ -0,0 +1,40 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"testing"
+)
+
+var pbkdf2Tests = []struct {
+	hash     func() hash.Hash
+	password string
+	salt     string
+	iter     int
+	keyLen   int
+	want     string
+}{
+	// From RFC 6070.
+	{sha1.New, "password", "salt", 1, 20, "0c60c80f961f0e71f3a9b524af6012062fe037a6"},
+	{sha1.New, "password", "salt", 4096, 20, "4b007901b765489abead49d926f721d065a429c1"},
+	{sha1.New, "passwordPASSWORDpassword", "saltSALTsaltSALTsaltSALTsaltSALTsalt", 4096, 25, "3d2eec4fe41c849b80c8d83662c0e44a8b291a964cf2f07038"},
+	// The key used by Chromium on Linux for v10 cookie values.
+	{sha1.New, "peanuts", "saltysalt", 1, 16, "fd621fe5a2b402539dfa147ca9272778"},
+	// From RFC 7914 section 11.
+	{sha256.New, "passwd", "salt", 1, 64, "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"},
+}
+
+func TestPBKDF2Key(t *testing.T) {
+	for _, test := range pbkdf2Tests {
+		got := hex.EncodeToString(pbkdf2Key(test.hash, []byte(test.password), []byte(test.salt), test.iter, test.keyLen))
+		if got != test.want {
+			t.Errorf("pbkdf2Key(%q, %q, %d, %d) = %s; want %s", test.password, test.salt, test.iter, test.keyLen, got, test.want)
+		}
+	}
+}
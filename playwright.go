@@ -0,0 +1,212 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+
+	"gopkg.in/errgo.v1"
+)
+
+// playwrightCookie holds a cookie as represented in Playwright's
+// storageState and by Puppeteer.
+type playwrightCookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+
+	// Domain holds the cookie's domain. It has a
+	// leading dot if the cookie is not host-only.
+	Domain string `json:"domain"`
+	Path   string `json:"path"`
+
+	// Expires holds the expiry time in seconds since
+	// the Unix epoch, or -1 for a session cookie.
+	Expires  float64 `json:"expires"`
+	HttpOnly bool    `json:"httpOnly"`
+	Secure   bool    `json:"secure"`
+	SameSite string  `json:"sameSite,omitempty"`
+
+	// PartitionKey holds the top-level site of a partitioned
+	// cookie. Puppeteer may represent it as an object holding
+	// the site in a sourceOrigin field.
+	PartitionKey json.RawMessage `json:"partitionKey,omitempty"`
+}
+
+// playwrightState holds a Playwright storageState.
+type playwrightState struct {
+	Cookies []playwrightCookie `json:"cookies"`
+
+	// Origins holds the local storage of each origin. It is
+	// not used by the jar but is preserved so that a
+	// storageState can be written that Playwright accepts.
+	Origins []json.RawMessage `json:"origins"`
+}
+
+// WritePlaywright writes all the unexpired cookies in the jar to w as a
+// Playwright storageState JSON object, which can be passed as the
+// storageState option when creating a Playwright browser context. The
+// origins field of the object is empty.
+func (j *Jar) WritePlaywright(w io.Writer) error {
+	return j.writePlaywright(w, time.Now())
+}
+
+// writePlaywright is like WritePlaywright but takes the current time as
+// a parameter.
+func (j *Jar) writePlaywright(w io.Writer, now time.Time) error {
+	state := playwrightState{
+		Cookies: []playwrightCookie{},
+		Origins: []json.RawMessage{},
+	}
+	for _, e := range j.allEntries(false, now) {
+		c := playwrightCookie{
+			Name:     e.Name,
+			Value:    e.Value,
+			Domain:   e.Domain,
+			Path:     e.Path,
+			Expires:  -1,
+			HttpOnly: e.HttpOnly,
+			Secure:   e.Secure,
+			SameSite: e.SameSite,
+		}
+		if !e.HostOnly {
+			c.Domain = "." + c.Domain
+		}
+		if e.Persistent {
+			c.Expires = float64(e.Expires.Unix())
+		}
+		if e.PartitionKey != "" {
+			c.PartitionKey, _ = json.Marshal(e.PartitionKey)
+		}
+		state.Cookies = append(state.Cookies, c)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	_, err = w.Write(append(data, '\n'))
+	return errgo.Mask(err)
+}
+
+// ReadPlaywright reads cookies from r and stores them in the jar.
+// The data may be a Playwright storageState JSON object or a JSON array
+// of cookies such as that returned by Playwright's
+// BrowserContext.cookies or Puppeteer's Page.cookies. Cookies read from
+// r replace cookies with the same name, domain and path already in the
+// jar, regardless of when those were last updated.
+//
+// Cookies for domains that the jar's public suffix list does not allow
+// cookies to be set for are ignored. An error is returned if any
+// cookie is malformed, in which case the jar is not changed.
+func (j *Jar) ReadPlaywright(r io.Reader) error {
+	return j.readPlaywright(r, time.Now())
+}
+
+// readPlaywright is like ReadPlaywright but takes the current time as
+// a parameter.
+func (j *Jar) readPlaywright(r io.Reader, now time.Time) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	var cookies []playwrightCookie
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(data, &cookies)
+	} else {
+		var state playwrightState
+		err = json.Unmarshal(data, &state)
+		cookies = state.Cookies
+	}
+	if err != nil {
+		return errgo.Notef(err, "cannot parse cookies")
+	}
+	var entries []entry
+	for i, c := range cookies {
+		e, ok, err := j.playwrightEntry(c, now)
+		if err != nil {
+			return errgo.Notef(err, "cookie %d (%q)", i, c.Name)
+		}
+		if ok {
+			entries = append(entries, e)
+		}
+	}
+	defer j.notify()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.replaceEntries(entries, now)
+	j.evict(now)
+	return nil
+}
+
+// playwrightEntry returns the entry for the given cookie. It returns
+// false if the cookie should be ignored.
+func (j *Jar) playwrightEntry(c playwrightCookie, now time.Time) (entry, bool, error) {
+	var e entry
+	if err := j.setImportedDomain(&e, c.Domain, !strings.HasPrefix(c.Domain, ".")); err != nil {
+		if _, ok := err.(*RejectError); ok {
+			return e, false, nil
+		}
+		return e, false, errgo.Mask(err)
+	}
+	e.Name = c.Name
+	e.Value = c.Value
+	e.Path = c.Path
+	if e.Path == "" || e.Path[0] != '/' {
+		e.Path = "/"
+	}
+	e.Secure = c.Secure
+	e.HttpOnly = c.HttpOnly
+	switch c.SameSite {
+	case "":
+	case "Strict", "Lax", "None":
+		e.SameSite = c.SameSite
+	default:
+		return e, false, errgo.Newf("invalid sameSite value %q", c.SameSite)
+	}
+	partitionKey, err := parsePlaywrightPartitionKey(c.PartitionKey)
+	if err != nil {
+		return e, false, errgo.Mask(err)
+	}
+	e.PartitionKey = partitionKey
+	if c.Expires < 0 {
+		e.Expires = endOfTime
+	} else {
+		sec, frac := math.Modf(c.Expires)
+		e.Persistent = true
+		e.Expires = time.Unix(int64(sec), int64(frac*1e9)).UTC()
+		if !e.Expires.After(now) {
+			return e, false, nil
+		}
+	}
+	e.Creation = now
+	e.LastAccess = now
+	e.Updated = now
+	return e, true, nil
+}
+
+// parsePlaywrightPartitionKey returns the top-level site held in the
+// given partitionKey field, which may be absent, a string, or an
+// object holding the site in its sourceOrigin field.
+func parsePlaywrightPartitionKey(data json.RawMessage) (string, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return "", nil
+	}
+	var key string
+	if err := json.Unmarshal(data, &key); err == nil {
+		return key, nil
+	}
+	var obj struct {
+		SourceOrigin string `json:"sourceOrigin"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return "", errgo.Newf("invalid partitionKey %s", data)
+	}
+	return obj.SourceOrigin, nil
+}
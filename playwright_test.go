@@ -0,0 +1,175 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestWritePlaywright(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	setCookies(jar, "https://www.host.test/foo/", []string{
+		"host=1",
+		"domain=2; domain=host.test; path=/; samesite=lax; " + expiresIn(100),
+		"expired=3; max-age=1",
+	}, tNow)
+	jar.setCookiesForRequest(mustParseURL("https://embed.test/"), mustParseURL("https://site.test/"), []*http.Cookie{{Name: "partitioned", Value: "4", Secure: true, HttpOnly: true, Partitioned: true}}, tNow)
+
+	var buf bytes.Buffer
+	err := jar.writePlaywright(&buf, atTime(10))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(buf.String(), qt.Equals, `{
+  "cookies": [
+    {
+      "name": "partitioned",
+      "value": "4",
+      "domain": "embed.test",
+      "path": "/",
+      "expires": -1,
+      "httpOnly": true,
+      "secure": true,
+      "partitionKey": "https://site.test"
+    },
+    {
+      "name": "host",
+      "value": "1",
+      "domain": "www.host.test",
+      "path": "/foo",
+      "expires": -1,
+      "httpOnly": false,
+      "secure": false
+    },
+    {
+      "name": "domain",
+      "value": "2",
+      "domain": ".host.test",
+      "path": "/",
+      "expires": 1357041700,
+      "httpOnly": false,
+      "secure": false,
+      "sameSite": "Lax"
+    }
+  ],
+  "origins": []
+}
+`)
+}
+
+func TestPlaywrightRoundTrip(t *testing.T) {
+	c := qt.New(t)
+	j0 := newTestJar("")
+	setCookies(j0, "https://www.host.test/foo/", []string{
+		"host=1; " + expiresIn(50),
+		"domain=2; domain=host.test; path=/; samesite=strict; " + expiresIn(100),
+		"secure=3; secure; httponly",
+	}, tNow)
+	j1 := checkRoundTrip(c, j0, (*Jar).writePlaywright, (*Jar).readPlaywright)
+
+	// Host-only cookies are not sent to subdomains.
+	c.Assert(queryJar(j1, "http://www.host.test/foo/", tNow), qt.Equals, "host=1 domain=2")
+	c.Assert(queryJar(j1, "https://sub.www.host.test/foo/", tNow), qt.Equals, "domain=2")
+}
+
+// puppeteerCookies holds cookies in the form returned
+// by Puppeteer's Page.cookies.
+const puppeteerCookies = `[{
+	"name": "a",
+	"value": "1",
+	"domain": "www.host.test",
+	"path": "/",
+	"expires": -1,
+	"size": 2,
+	"httpOnly": false,
+	"secure": false,
+	"session": true
+}, {
+	"name": "b",
+	"value": "2",
+	"domain": ".host.test",
+	"path": "/x",
+	"expires": 1357041700.25,
+	"size": 2,
+	"httpOnly": true,
+	"secure": true,
+	"session": false,
+	"sameSite": "None",
+	"partitionKey": {"sourceOrigin": "https://site.test", "hasCrossSiteAncestor": false}
+}, {
+	"name": "expired",
+	"value": "3",
+	"domain": "www.host.test",
+	"path": "/",
+	"expires": 1
+}, {
+	"name": "publicsuffix",
+	"value": "4",
+	"domain": ".co.uk",
+	"path": "/",
+	"expires": -1
+}]`
+
+func TestReadPlaywrightPuppeteer(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	setCookies(jar, "http://www.host.test/", []string{"keep=1"}, tNow)
+	// Cookies read replace those in the jar even when the
+	// jar's cookie was updated more recently.
+	setCookies(jar, "http://www.host.test/", []string{"a=old; max-age=1000"}, atTime(100))
+	err := jar.readPlaywright(strings.NewReader(puppeteerCookies), atTime(1))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(allCookies(jar, atTime(1)), qt.Equals, "a=1 b=2 keep=1 publicsuffix=4")
+	c.Assert(queryJar(jar, "http://www.host.test/", atTime(1)), qt.Equals, "keep=1 a=1")
+
+	entries := jar.allEntries(false, atTime(1))
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	b := entries[1]
+	c.Assert(b.Name, qt.Equals, "b")
+	c.Assert(b.Domain, qt.Equals, "host.test")
+	c.Assert(b.HostOnly, qt.Equals, false)
+	c.Assert(b.Persistent, qt.Equals, true)
+	c.Assert(b.Expires, qt.DeepEquals, tNow.Add(100e9+250e6))
+	c.Assert(b.SameSite, qt.Equals, "None")
+	c.Assert(b.PartitionKey, qt.Equals, "https://site.test")
+
+	// As with cookies set by a server, a domain cookie for a
+	// public suffix is treated as a host cookie.
+	c.Assert(entries[3].Name, qt.Equals, "publicsuffix")
+	c.Assert(entries[3].HostOnly, qt.Equals, true)
+	c.Assert(queryJar(jar, "http://www.host.co.uk/", atTime(1)), qt.Equals, "")
+}
+
+var readPlaywrightErrorTests = []struct {
+	data   string
+	expect string
+}{{
+	data:   `{"cookies": [`,
+	expect: `cannot parse cookies: unexpected end of JSON input`,
+}, {
+	data:   `{"cookies": [{"name": "ok", "domain": "host.test", "expires": -1}, {"name": "a", "domain": ".", "expires": -1}]}`,
+	expect: `cookie 1 \("a"\): invalid domain "."`,
+}, {
+	data:   `[{"name": "a", "domain": "host.test", "expires": -1, "sameSite": "Sometimes"}]`,
+	expect: `cookie 0 \("a"\): invalid sameSite value "Sometimes"`,
+}, {
+	data:   `[{"name": "a", "domain": "host.test", "expires": -1, "partitionKey": 1}]`,
+	expect: `cookie 0 \("a"\): invalid partitionKey 1`,
+}}
+
+func TestReadPlaywrightError(t *testing.T) {
+	c := qt.New(t)
+	for _, test := range readPlaywrightErrorTests {
+		jar := newTestJar("")
+		err := jar.readPlaywright(strings.NewReader(test.data), tNow)
+		c.Assert(err, qt.ErrorMatches, test.expect)
+		c.Assert(allCookies(jar, tNow), qt.Equals, "")
+	}
+}
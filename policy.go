@@ -0,0 +1,294 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/errgo.v1"
+)
+
+// Policy decides whether a cookie received from a server may be
+// stored in a jar. See Options.Policy.
+type Policy interface {
+	// CheckCookie is called for each cookie received from the URL
+	// u. The cookie is a copy that the policy is free to modify.
+	//
+	// If CheckCookie returns an error, the cookie is rejected;
+	// SetCookiesWithResults reports a *RejectError with
+	// RulePolicy that holds the error. Otherwise the returned
+	// cookie is stored in place of the original one, subject to
+	// the jar's own rules.
+	CheckCookie(u *url.URL, c *http.Cookie) (*http.Cookie, error)
+}
+
+// PolicyFunc implements Policy by calling the function itself.
+type PolicyFunc func(u *url.URL, c *http.Cookie) (*http.Cookie, error)
+
+// CheckCookie implements Policy.CheckCookie.
+func (f PolicyFunc) CheckCookie(u *url.URL, c *http.Cookie) (*http.Cookie, error) {
+	return f(u, c)
+}
+
+// checkPolicy checks the given cookie received from u against the
+// jar's policy and returns the cookie to store. An error
+// returned by the policy is reported as a *RejectError.
+func (j *Jar) checkPolicy(u *url.URL, cookie *http.Cookie) (*http.Cookie, error) {
+	if j.policy == nil {
+		return cookie, nil
+	}
+	c := *cookie
+	checked, err := j.policy.CheckCookie(u, &c)
+	if err != nil {
+		return nil, &RejectError{Rule: RulePolicy, Err: err}
+	}
+	if checked == nil {
+		return nil, &RejectError{Rule: RulePolicy}
+	}
+	return checked, nil
+}
+
+// Policies returns a policy that applies each of the given policies in
+// turn, passing the cookie returned by each to the next. A cookie is
+// rejected if any of the policies rejects it.
+func Policies(ps ...Policy) Policy {
+	return PolicyFunc(func(u *url.URL, c *http.Cookie) (*http.Cookie, error) {
+		for _, p := range ps {
+			var err error
+			c, err = p.CheckCookie(u, c)
+			if err != nil {
+				return nil, err
+			}
+			if c == nil {
+				return nil, errgo.New("cookie rejected")
+			}
+		}
+		return c, nil
+	})
+}
+
+// AllowDomains returns a policy that rejects all cookies except those
+// received from hosts that match one of the given domain patterns.
+//
+// A pattern matches a host name that is equal to it or is a subdomain
+// of it, so "example.com" matches both "example.com" and
+// "www.example.com". A leading "*." or "." in a pattern is ignored.
+func AllowDomains(patterns ...string) Policy {
+	allowed := newDomainSet(patterns)
+	return PolicyFunc(func(u *url.URL, c *http.Cookie) (*http.Cookie, error) {
+		if !allowed.matchURL(u) {
+			return nil, errgo.Newf("host %q is not in the allowed domains", u.Host)
+		}
+		return c, nil
+	})
+}
+
+// DenyDomains returns a policy that rejects all cookies received from
+// hosts that match any of the given domain patterns. Patterns are
+// interpreted as for AllowDomains.
+func DenyDomains(patterns ...string) Policy {
+	return denyDomains(newDomainSet(patterns))
+}
+
+func denyDomains(denied domainSet) Policy {
+	return PolicyFunc(func(u *url.URL, c *http.Cookie) (*http.Cookie, error) {
+		if denied.matchURL(u) {
+			return nil, errgo.Newf("host %q is in a denied domain", u.Host)
+		}
+		return c, nil
+	})
+}
+
+// MaxLifetime returns a policy that limits the lifetime of persistent
+// cookies to at most d from the time they are received. Session
+// cookies and cookies that delete existing cookies are not changed.
+// As cookie lifetimes are measured in seconds, d is rounded up to a
+// whole number of seconds, and to at least one second.
+func MaxLifetime(d time.Duration) Policy {
+	maxAge := int((d + time.Second - 1) / time.Second)
+	if maxAge < 1 {
+		// A MaxAge of zero would mean no limit at all.
+		maxAge = 1
+	}
+	return PolicyFunc(func(u *url.URL, c *http.Cookie) (*http.Cookie, error) {
+		switch {
+		case c.MaxAge > maxAge:
+			c.MaxAge = maxAge
+		case c.MaxAge == 0 && c.Expires.After(time.Now().Add(d)):
+			// MaxAge takes precedence over Expires,
+			// and is relative to the time that the
+			// jar stores the cookie.
+			c.MaxAge = maxAge
+		}
+		return c, nil
+	})
+}
+
+// SessionOnly returns a policy that turns persistent cookies received
+// from hosts that match any of the given domain patterns into session
+// cookies. Patterns are interpreted as for AllowDomains. Cookies that
+// delete existing cookies are not changed.
+func SessionOnly(patterns ...string) Policy {
+	session := newDomainSet(patterns)
+	return PolicyFunc(func(u *url.URL, c *http.Cookie) (*http.Cookie, error) {
+		if !session.matchURL(u) {
+			return c, nil
+		}
+		if c.MaxAge < 0 || c.MaxAge == 0 && !c.Expires.IsZero() && !c.Expires.After(time.Now()) {
+			return c, nil
+		}
+		c.MaxAge = 0
+		c.Expires = time.Time{}
+		c.RawExpires = ""
+		return c, nil
+	})
+}
+
+// LoadBlockList returns a policy that rejects cookies received from
+// the domains listed in the given file, as for DenyDomains.
+//
+// The file may use any mixture of the following formats, one entry
+// per line:
+//
+//	||example.com^      adblock-style domain rule
+//	0.0.0.0 example.com hosts file entry
+//	example.com         plain domain name
+//
+// Options following a "$" in adblock-style rules are ignored, so the
+// domain is always blocked. Empty lines, comments starting with "!",
+// "[" or "#", and rules in any other format (for example exception
+// rules and URL patterns) are ignored.
+func LoadBlockList(filename string) (Policy, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	defer f.Close()
+	domains, err := readBlockList(f)
+	if err != nil {
+		return nil, errgo.Notef(err, "cannot read block list %q", filename)
+	}
+	return denyDomains(newDomainSet(domains)), nil
+}
+
+// readBlockList returns the domains listed in the block list read
+// from r. See LoadBlockList for the format.
+func readBlockList(r io.Reader) ([]string, error) {
+	var domains []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "!") || strings.HasPrefix(line, "[") {
+			continue
+		}
+		if strings.HasPrefix(line, "||") {
+			rule := line[2:]
+			if i := strings.IndexByte(rule, '$'); i >= 0 {
+				rule = rule[:i]
+			}
+			if !strings.HasSuffix(rule, "^") {
+				continue
+			}
+			if domain := rule[:len(rule)-1]; isBlockListDomain(domain) {
+				domains = append(domains, domain)
+			}
+			continue
+		}
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			if i > 0 && line[i-1] != ' ' && line[i-1] != '\t' {
+				// An element hiding rule such as "example.com##.ad".
+				continue
+			}
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 1:
+			if isBlockListDomain(fields[0]) {
+				domains = append(domains, fields[0])
+			}
+		case len(fields) > 1 && net.ParseIP(fields[0]) != nil:
+			for _, domain := range fields[1:] {
+				if isBlockListDomain(domain) {
+					domains = append(domains, domain)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errgo.Mask(err)
+	}
+	return domains, nil
+}
+
+// isBlockListDomain reports whether s looks like a domain name that
+// should be blocked. Host names without a dot, such as "localhost",
+// are commonly found in hosts files but are never blocked.
+func isBlockListDomain(s string) bool {
+	if !strings.Contains(s, ".") || net.ParseIP(s) != nil {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		case r == '.', r == '-', r == '_', r >= 0x80:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// domainSet holds a set of canonical domain names.
+type domainSet map[string]bool
+
+// newDomainSet returns the set of domains specified by the given
+// patterns. See AllowDomains for the pattern syntax.
+func newDomainSet(patterns []string) domainSet {
+	s := make(domainSet)
+	for _, p := range patterns {
+		p = strings.TrimPrefix(p, "*")
+		p = strings.TrimPrefix(p, ".")
+		if host, err := canonicalHost(p); err == nil {
+			p = host
+		}
+		if p != "" {
+			s[strings.ToLower(p)] = true
+		}
+	}
+	return s
+}
+
+// matchURL reports whether the host of u is in the set
+// or is a subdomain of a domain in the set.
+func (s domainSet) matchURL(u *url.URL) bool {
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return false
+	}
+	return s.match(host)
+}
+
+// match reports whether the given canonical host name is in the set
+// or is a subdomain of a domain in the set.
+func (s domainSet) match(host string) bool {
+	for {
+		if s[host] {
+			return true
+		}
+		i := strings.IndexByte(host, '.')
+		if i < 0 {
+			return false
+		}
+		host = host[i+1:]
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"gopkg.in/errgo.v1"
+)
+
+func newPolicyJar(p Policy) *Jar {
+	jar, err := New(&Options{
+		PublicSuffixList: testPSL{},
+		NoPersist:        true,
+		Policy:           p,
+	})
+	if err != nil {
+		panic(err)
+	}
+	return jar
+}
+
+var policyTests = []struct {
+	about   string
+	policy  Policy
+	fromURL string
+	cookies []string
+	want    string
+}{{
+	about:   "allowed domain",
+	policy:  AllowDomains("host.test"),
+	fromURL: "http://www.host.test",
+	cookies: []string{"a=1"},
+	want:    "a=1",
+}, {
+	about:   "domain not allowed",
+	policy:  AllowDomains("*.other.test", "www.host.test"),
+	fromURL: "http://host.test",
+	cookies: []string{"a=1"},
+	want:    "",
+}, {
+	about:   "denied domain",
+	policy:  DenyDomains(".host.test"),
+	fromURL: "http://www.HOST.test.",
+	cookies: []string{"a=1"},
+	want:    "",
+}, {
+	about:   "domain not denied",
+	policy:  DenyDomains("www.host.test"),
+	fromURL: "http://host.test",
+	cookies: []string{"a=1"},
+	want:    "a=1",
+}, {
+	about:   "session only",
+	policy:  SessionOnly("host.test"),
+	fromURL: "http://www.host.test",
+	cookies: []string{"a=1; max-age=3600", "b=1; expires=Fri, 31 Dec 9999 23:59:59 GMT"},
+	want:    "a=1 b=1",
+}, {
+	about:   "chained policies",
+	policy:  Policies(AllowDomains("host.test"), DenyDomains("www.host.test")),
+	fromURL: "http://www.host.test",
+	cookies: []string{"a=1"},
+	want:    "",
+}}
+
+func TestPolicy(t *testing.T) {
+	for _, test := range policyTests {
+		jar := newPolicyJar(test.policy)
+		setCookies(jar, test.fromURL, test.cookies, tNow)
+		if got := allCookies(jar, tNow); got != test.want {
+			t.Errorf("%s: got %q want %q", test.about, got, test.want)
+		}
+	}
+}
+
+func TestPolicyRejection(t *testing.T) {
+	c := qt.New(t)
+	policyErr := errgo.New("no thanks")
+	var called []string
+	var jar *Jar
+	jar = newPolicyJar(PolicyFunc(func(u *url.URL, cookie *http.Cookie) (*http.Cookie, error) {
+		// The policy must be free to use the jar.
+		jar.Cookies(u)
+		called = append(called, u.Host+" "+cookie.Name)
+		if cookie.Name == "bad" {
+			return nil, policyErr
+		}
+		cookie.Value = "modified"
+		return cookie, nil
+	}))
+	cookies := []*http.Cookie{{Name: "good", Value: "1"}, {Name: "bad", Value: "1"}}
+	results := jar.SetCookiesWithResults(mustParseURL("http://www.host.test/"), cookies)
+	c.Assert(called, qt.DeepEquals, []string{"www.host.test good", "www.host.test bad"})
+	c.Assert(len(results), qt.Equals, 2)
+	c.Assert(results[0].Outcome, qt.Equals, OutcomeStored)
+	c.Assert(results[1].Outcome, qt.Equals, OutcomeRejected)
+	c.Assert(results[1].Err, qt.ErrorMatches, "cookiejar: cookie rejected by policy: no thanks")
+	c.Assert(errgo.Cause(results[1].Err), qt.Equals, policyErr)
+	c.Assert(results[1].Err.(*RejectError).Rule, qt.Equals, RulePolicy)
+
+	// The policy is passed a copy of the cookie.
+	c.Assert(cookies[0].Value, qt.Equals, "1")
+	c.Assert(jar.Cookies(mustParseURL("http://www.host.test/")), qt.DeepEquals, []*http.Cookie{{Name: "good", Value: "modified"}})
+}
+
+func TestPolicyJarRulesStillApply(t *testing.T) {
+	c := qt.New(t)
+	jar := newPolicyJar(PolicyFunc(func(u *url.URL, cookie *http.Cookie) (*http.Cookie, error) {
+		cookie.Domain = "other.test"
+		return cookie, nil
+	}))
+	results := jar.SetCookiesWithResults(mustParseURL("http://www.host.test/"), []*http.Cookie{{Name: "a", Value: "1"}})
+	c.Assert(results[0].Outcome, qt.Equals, OutcomeRejected)
+	c.Assert(results[0].Err, qt.Equals, errIllegalDomain)
+}
+
+func TestMaxLifetime(t *testing.T) {
+	c := qt.New(t)
+	jar := newPolicyJar(MaxLifetime(time.Hour))
+	setCookies(jar, "http://www.host.test/", []string{
+		"a=1; max-age=86400",
+		"b=1; expires=Fri, 31 Dec 9999 23:59:59 GMT",
+		"c=1; max-age=60",
+		"d=1",
+	}, tNow)
+	c.Assert(allCookies(jar, tNow.Add(59*time.Minute)), qt.Equals, "a=1 b=1 d=1")
+	c.Assert(allCookies(jar, tNow.Add(61*time.Minute)), qt.Equals, "d=1")
+
+	// Deletions are not affected.
+	setCookies(jar, "http://www.host.test/", []string{"d=1; max-age=-1"}, tNow)
+	c.Assert(allCookies(jar, tNow), qt.Equals, "a=1 b=1 c=1")
+}
+
+func TestMaxLifetimeShort(t *testing.T) {
+	c := qt.New(t)
+	jar := newPolicyJar(MaxLifetime(500 * time.Millisecond))
+	setCookies(jar, "http://www.host.test/", []string{
+		"a=1; max-age=86400",
+		"b=1; expires=Fri, 31 Dec 9999 23:59:59 GMT",
+	}, tNow)
+	c.Assert(allCookies(jar, tNow), qt.Equals, "a=1 b=1")
+	c.Assert(len(jar.allPersistentEntries()), qt.Equals, 2)
+	c.Assert(allCookies(jar, tNow.Add(time.Second)), qt.Equals, "")
+}
+
+func TestSessionOnlyPersistence(t *testing.T) {
+	c := qt.New(t)
+	jar := newPolicyJar(SessionOnly("host.test"))
+	setCookies(jar, "http://www.host.test/", []string{"a=1; max-age=3600"}, tNow)
+	setCookies(jar, "http://www.other.test/", []string{"b=1; max-age=3600"}, tNow)
+	c.Assert(allCookies(jar, tNow), qt.Equals, "a=1 b=1")
+	var persistent []string
+	for _, e := range jar.allPersistentEntries() {
+		persistent = append(persistent, e.Name)
+	}
+	c.Assert(persistent, qt.DeepEquals, []string{"b"})
+}
+
+const testBlockList = `
+[Adblock Plus 2.0]
+! Title: test list
+||ads.test^
+||tracker.test^$third-party
+@@||allowed.test^
+||example.test/path^
+example.test##.banner
+# hosts file
+127.0.0.1 localhost
+0.0.0.0 metrics.test stats.test # inline comment
+::1 ip6-localhost
+plain.test
+`
+
+func TestReadBlockList(t *testing.T) {
+	c := qt.New(t)
+	domains, err := readBlockList(strings.NewReader(testBlockList))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(domains, qt.DeepEquals, []string{
+		"ads.test",
+		"tracker.test",
+		"metrics.test",
+		"stats.test",
+		"plain.test",
+	})
+}
+
+func TestLoadBlockList(t *testing.T) {
+	c := qt.New(t)
+	dir, err := ioutil.TempDir("", "cookiejar-test")
+	c.Assert(err, qt.Equals, nil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "blocklist.txt")
+	err = ioutil.WriteFile(path, []byte(testBlockList), 0600)
+	c.Assert(err, qt.Equals, nil)
+
+	p, err := LoadBlockList(path)
+	c.Assert(err, qt.Equals, nil)
+	jar := newPolicyJar(p)
+	for _, host := range []string{"ads.test", "www.tracker.test", "stats.test", "plain.test", "allowed.test", "example.test"} {
+		setCookies(jar, "http://"+host+"/", []string{"a=" + host}, tNow)
+	}
+	c.Assert(allCookies(jar, tNow), qt.Equals, "a=allowed.test a=example.test")
+
+	_, err = LoadBlockList(filepath.Join(dir, "nonexistent"))
+	c.Assert(err, qt.ErrorMatches, "open .*: no such file or directory")
+}
@@ -0,0 +1,234 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"strings"
+	"time"
+
+	"gopkg.in/errgo.v1"
+)
+
+// This file implements a reader for the undocumented Cookies.binarycookies
+// format used by Safari and other applications that use CFNetwork. The
+// file starts with a header holding the size of each page, in big-endian
+// byte order:
+//
+//	"cook"           magic
+//	uint32           number of pages
+//	uint32...        size of each page
+//
+// Each page, in little-endian byte order apart from its magic, holds
+// the offsets of its cookie records:
+//
+//	00 00 01 00      magic
+//	uint32           number of cookies
+//	uint32...        offset of each cookie in the page
+//	00 00 00 00      end of header
+//
+// Each cookie record, in little-endian byte order, holds:
+//
+//	0  uint32        size of the record
+//	4  uint32        unknown
+//	8  uint32        flags
+//	12 uint32        unknown
+//	16 uint32        offset of domain
+//	20 uint32        offset of name
+//	24 uint32        offset of path
+//	28 uint32        offset of value
+//	32 [8]byte       unknown
+//	40 float64       expiry time
+//	48 float64       creation time
+//	56 ...           NUL-terminated strings
+//
+// The pages are followed by a checksum and other data that is ignored.
+
+const safariMagic = "cook"
+
+// safariPageMagic is the start of each page in a binarycookies file.
+var safariPageMagic = []byte{0, 0, 1, 0}
+
+// safariRecordSize holds the size of the fixed part of a cookie record.
+const safariRecordSize = 56
+
+const (
+	safariSecure   = 1
+	safariHttpOnly = 4
+)
+
+// macEpochOffset holds the number of seconds between the Unix epoch
+// and 2001-01-01, which Mac absolute times are measured from.
+const macEpochOffset = 978307200
+
+// safariCookie holds a cookie record from a binarycookies file.
+type safariCookie struct {
+	domain, name, path, value string
+	flags                     uint32
+	expires, creation         time.Time
+}
+
+// ImportSafari merges the cookies from the given Safari
+// Cookies.binarycookies file into the jar. On macOS, Safari keeps the
+// file in ~/Library/Containers/com.apple.Safari/Data/Library/Cookies;
+// it can be copied to other systems and imported there.
+//
+// Safari does not store session cookies in the file, so all the
+// imported cookies are persistent. As the file does not record when
+// cookies were last updated, a cookie replaces one already in the jar
+// only if it was created in Safari more recently than the jar's cookie
+// was updated.
+func (j *Jar) ImportSafari(filename string) error {
+	return j.importSafari(filename, time.Now())
+}
+
+// importSafari is like ImportSafari but takes the current time as a
+// parameter.
+func (j *Jar) importSafari(filename string, now time.Time) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return errgo.Notef(err, "cannot read %q", filename)
+	}
+	cookies, err := parseBinaryCookies(data)
+	if err != nil {
+		return errgo.Notef(err, "cannot read Safari cookies from %q", filename)
+	}
+	var entries []entry
+	for _, c := range cookies {
+		if e, ok := j.safariEntry(c, now); ok {
+			entries = append(entries, e)
+		}
+	}
+	defer j.notify()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.merge(entries) {
+		j.changed()
+		j.evict(now)
+	}
+	return nil
+}
+
+// safariEntry returns the entry for the given cookie record. It returns
+// false if the cookie should be ignored.
+func (j *Jar) safariEntry(c safariCookie, now time.Time) (entry, bool) {
+	var e entry
+	if err := j.setImportedDomain(&e, c.domain, !strings.HasPrefix(c.domain, ".")); err != nil {
+		return e, false
+	}
+	e.Name = c.name
+	e.Value = c.value
+	e.Path = c.path
+	if e.Path == "" || e.Path[0] != '/' {
+		e.Path = "/"
+	}
+	e.Secure = c.flags&safariSecure != 0
+	e.HttpOnly = c.flags&safariHttpOnly != 0
+	e.Persistent = true
+	e.Expires = c.expires
+	if !e.Expires.After(now) {
+		return e, false
+	}
+	e.Creation = c.creation
+	if e.Creation.IsZero() {
+		e.Creation = now
+	}
+	e.LastAccess = e.Creation
+	e.Updated = e.Creation
+	return e, true
+}
+
+// parseBinaryCookies returns the cookie records in the given
+// binarycookies data.
+func parseBinaryCookies(data []byte) ([]safariCookie, error) {
+	if len(data) < 8 || string(data[:4]) != safariMagic {
+		return nil, errgo.New("not a binarycookies file")
+	}
+	n := binary.BigEndian.Uint32(data[4:])
+	if uint64(n) > uint64(len(data)-8)/4 {
+		return nil, errgo.New("too many pages")
+	}
+	offset := 8 + 4*int(n)
+	var cookies []safariCookie
+	for i := 0; i < int(n); i++ {
+		size := binary.BigEndian.Uint32(data[8+4*i:])
+		if uint64(size) > uint64(len(data)-offset) {
+			return nil, errgo.Newf("page %d truncated", i)
+		}
+		pageCookies, err := parseSafariPage(data[offset : offset+int(size)])
+		if err != nil {
+			return nil, errgo.Notef(err, "page %d", i)
+		}
+		cookies = append(cookies, pageCookies...)
+		offset += int(size)
+	}
+	return cookies, nil
+}
+
+// parseSafariPage returns the cookie records in the given page.
+func parseSafariPage(page []byte) ([]safariCookie, error) {
+	if len(page) < 8 || !bytes.Equal(page[:4], safariPageMagic) {
+		return nil, errgo.New("invalid page header")
+	}
+	n := binary.LittleEndian.Uint32(page[4:])
+	if uint64(n) > uint64(len(page)-8)/4 {
+		return nil, errgo.New("too many cookies")
+	}
+	cookies := make([]safariCookie, n)
+	for i := range cookies {
+		offset := binary.LittleEndian.Uint32(page[8+4*i:])
+		if uint64(offset) >= uint64(len(page)) {
+			return nil, errgo.Newf("cookie %d out of range", i)
+		}
+		c, err := parseSafariCookie(page[offset:])
+		if err != nil {
+			return nil, errgo.Notef(err, "cookie %d", i)
+		}
+		cookies[i] = c
+	}
+	return cookies, nil
+}
+
+// parseSafariCookie parses the cookie record at the start of data.
+func parseSafariCookie(data []byte) (safariCookie, error) {
+	var c safariCookie
+	if len(data) < safariRecordSize {
+		return c, errgo.New("truncated record")
+	}
+	size := binary.LittleEndian.Uint32(data)
+	if size < safariRecordSize || uint64(size) > uint64(len(data)) {
+		return c, errgo.Newf("invalid record size %d", size)
+	}
+	data = data[:size]
+	c.flags = binary.LittleEndian.Uint32(data[8:])
+	for i, s := range []*string{&c.domain, &c.name, &c.path, &c.value} {
+		offset := binary.LittleEndian.Uint32(data[16+4*i:])
+		if offset < safariRecordSize || uint64(offset) >= uint64(len(data)) {
+			return c, errgo.Newf("invalid string offset %d", offset)
+		}
+		str := data[offset:]
+		end := bytes.IndexByte(str, 0)
+		if end < 0 {
+			return c, errgo.New("unterminated string")
+		}
+		*s = string(str[:end])
+	}
+	c.expires = macTime(math.Float64frombits(binary.LittleEndian.Uint64(data[40:])))
+	c.creation = macTime(math.Float64frombits(binary.LittleEndian.Uint64(data[48:])))
+	return c, nil
+}
+
+// macTime returns the time held in the given Mac absolute time, in
+// seconds since 2001-01-01. It returns the zero time if t is zero.
+func macTime(t float64) time.Time {
+	if t == 0 || math.IsNaN(t) || math.IsInf(t, 0) {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(t)
+	return time.Unix(int64(sec)+macEpochOffset, int64(frac*1e9)).UTC()
+}
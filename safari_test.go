@@ -0,0 +1,170 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+// The Safari fixture in testdata is generated by mksafari.py, which
+// encodes the same reading of the undocumented format as the parser,
+// so it cannot catch a misread field. A file exported from Safari
+// itself should be added alongside it.
+
+const safariCookiesFile = "testdata/safari/Cookies.binarycookies"
+
+func TestImportSafari(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	err := jar.importSafari(safariCookiesFile, firefoxNow)
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(jar.dirty, qt.Equals, true)
+	c.Assert(allCookies(jar, firefoxNow), qt.Equals, "domain=d empty= host=abc port=8080 publicsuffix=p")
+
+	created := time.Unix(1700000000, 5e8).UTC()
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.Assert(jar.entries["example.com"], qt.DeepEquals, map[string]entry{
+		"www.example.com;/;host": {
+			Name:          "host",
+			Value:         "abc",
+			Domain:        "www.example.com",
+			Path:          "/",
+			Secure:        true,
+			HttpOnly:      true,
+			Persistent:    true,
+			HostOnly:      true,
+			Expires:       expires,
+			Creation:      created,
+			LastAccess:    created,
+			Updated:       created,
+			CanonicalHost: "www.example.com",
+		},
+		"example.com;/app;domain": {
+			Name:          "domain",
+			Value:         "d",
+			Domain:        "example.com",
+			Path:          "/app",
+			Persistent:    true,
+			Expires:       expires,
+			Creation:      created,
+			LastAccess:    created,
+			Updated:       created,
+			CanonicalHost: "example.com",
+		},
+	})
+	c.Assert(queryJar(jar, "https://sub.example.com/app/x", firefoxNow), qt.Equals, "domain=d")
+	// The port and comment of a cookie are ignored.
+	c.Assert(queryJar(jar, "https://other.test/", firefoxNow), qt.Equals, "port=8080 empty=")
+	c.Assert(queryJar(jar, "http://other.test:80/", firefoxNow), qt.Equals, "port=8080")
+
+	// As with cookies set by a server, a domain cookie for a
+	// public suffix is treated as a host cookie.
+	c.Assert(queryJar(jar, "https://www.host.co.uk/", firefoxNow), qt.Equals, "")
+	c.Assert(queryJar(jar, "https://co.uk/", firefoxNow), qt.Equals, "publicsuffix=p")
+}
+
+func TestImportSafariNewestWins(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	setCookies(jar, "https://www.example.com/", []string{"host=newer"}, time.Unix(1740000000, 0))
+	setCookies(jar, "https://other.test/", []string{"empty=older"}, time.Unix(1705000000, 0))
+	err := jar.importSafari(safariCookiesFile, firefoxNow)
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(queryJar(jar, "https://www.example.com/", firefoxNow), qt.Equals, "host=newer")
+	c.Assert(queryJar(jar, "https://other.test/", firefoxNow), qt.Equals, "port=8080 empty=")
+}
+
+func TestImportSafariNotFound(t *testing.T) {
+	c := qt.New(t)
+	jar := newTestJar("")
+	err := jar.importSafari("testdata/safari/nonexistent", firefoxNow)
+	c.Assert(err, qt.ErrorMatches, `cannot read "testdata/safari/nonexistent": .*`)
+}
+
+var parseBinaryCookiesErrorTests = []struct {
+	about  string
+	change func(data []byte) []byte
+	expect string
+}{{
+	about: "bad magic",
+	change: func(data []byte) []byte {
+		return append([]byte("kooc"), data[4:]...)
+	},
+	expect: "not a binarycookies file",
+}, {
+	about: "too many pages",
+	change: func(data []byte) []byte {
+		binary.BigEndian.PutUint32(data[4:], 1e6)
+		return data
+	},
+	expect: "too many pages",
+}, {
+	about: "truncated page",
+	change: func(data []byte) []byte {
+		return data[:300]
+	},
+	expect: "page 1 truncated",
+}, {
+	about: "bad page magic",
+	change: func(data []byte) []byte {
+		data[16] = 1
+		return data
+	},
+	expect: "page 0: invalid page header",
+}, {
+	about: "too many cookies",
+	change: func(data []byte) []byte {
+		binary.LittleEndian.PutUint32(data[20:], 1000)
+		return data
+	},
+	expect: "page 0: too many cookies",
+}, {
+	about: "cookie out of range",
+	change: func(data []byte) []byte {
+		binary.LittleEndian.PutUint32(data[24:], 1000)
+		return data
+	},
+	expect: "page 0: cookie 0 out of range",
+}, {
+	about: "bad record size",
+	change: func(data []byte) []byte {
+		binary.LittleEndian.PutUint32(data[16+0x18:], 1000)
+		return data
+	},
+	expect: "page 0: cookie 0: invalid record size 1000",
+}, {
+	about: "bad string offset",
+	change: func(data []byte) []byte {
+		binary.LittleEndian.PutUint32(data[16+0x18+20:], 10)
+		return data
+	},
+	expect: "page 0: cookie 0: invalid string offset 10",
+}, {
+	about: "unterminated string",
+	change: func(data []byte) []byte {
+		// Overwrite the NUL at the end of the
+		// value of the first cookie.
+		data[16+0x18+0x52] = 'x'
+		return data
+	},
+	expect: "page 0: cookie 0: unterminated string",
+}}
+
+func TestParseBinaryCookiesError(t *testing.T) {
+	c := qt.New(t)
+	for _, test := range parseBinaryCookiesErrorTests {
+		data, err := ioutil.ReadFile(safariCookiesFile)
+		c.Assert(err, qt.Equals, nil)
+		_, err = parseBinaryCookies(test.change(data))
+		if err == nil || err.Error() != test.expect {
+			t.Errorf("%s: got error %v want %q", test.about, err, test.expect)
+		}
+	}
+}
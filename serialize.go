@@ -5,11 +5,11 @@
 package cookiejar
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"io"
 	"log"
-	"os"
-	"path/filepath"
 	"sort"
 	"time"
 
@@ -19,18 +19,76 @@ import (
 	"gopkg.in/errgo.v1"
 )
 
-// Save saves the cookies to the persistent cookie file.
-// Before the file is written, it reads any cookies that
-// have been stored from it and merges them into j.
+// Save saves the cookies to the persistent cookie file
+// or other storage.
+// Before the cookies are written, it reads any cookies that
+// have been stored and merges them into j. The storage
+// is locked for the duration.
+//
+// The merge is skipped when the stored cookies have not changed
+// since they were last loaded or saved, and the write is skipped
+// when no cookies in j have changed either, or when the merged
+// cookies are exactly those already stored.
 func (j *Jar) Save() error {
-	if j.filename == "" {
+	if j.storage == nil {
 		return nil
 	}
 	return j.save(time.Now())
 }
 
+// Close stops the jar from being saved automatically (see
+// Options.AutoSaveDelay) and from watching its storage for changes
+// (see Options.Watch). If automatic saving is enabled, any changes
+// that have not yet been saved are saved first. The jar may still be
+// used after Close is called.
+func (j *Jar) Close() error {
+	j.stopWatching()
+	j.mu.Lock()
+	j.closed = true
+	if j.saveTimer != nil {
+		j.saveTimer.Stop()
+		j.saveTimer = nil
+	}
+	j.mu.Unlock()
+	// Wait for any automatic save that is in progress.
+	j.autoSaveMu.Lock()
+	defer j.autoSaveMu.Unlock()
+	if j.autoSaveDelay == 0 {
+		return nil
+	}
+	return j.Save()
+}
+
+// autoSave is called by j.saveTimer to save the jar.
+func (j *Jar) autoSave() {
+	// The error is handled after autoSaveMu is released
+	// so that j.onSaveError may call Close.
+	if err := j.saveUnlessClosed(); err != nil {
+		if j.onSaveError != nil {
+			j.onSaveError(err)
+		} else {
+			log.Printf("cannot save cookies: %v", err)
+		}
+	}
+}
+
+// saveUnlessClosed saves the jar if Close has not been called.
+func (j *Jar) saveUnlessClosed() error {
+	j.autoSaveMu.Lock()
+	defer j.autoSaveMu.Unlock()
+	j.mu.Lock()
+	j.saveTimer = nil
+	closed := j.closed
+	j.mu.Unlock()
+	if closed {
+		return nil
+	}
+	return j.Save()
+}
+
 // MarshalJSON implements json.Marshaler by encoding all persistent cookies
-// currently in the jar.
+// currently in the jar, and also session cookies if
+// Options.PersistSessionCookies was set.
 func (j *Jar) MarshalJSON() ([]byte, error) {
 	j.mu.Lock()
 	defer j.mu.Unlock()
@@ -41,63 +99,153 @@ func (j *Jar) MarshalJSON() ([]byte, error) {
 
 // save is like Save but takes the current time as a parameter.
 func (j *Jar) save(now time.Time) error {
-	locked, err := lockFile(lockFileName(j.filename))
+	defer j.notify()
+	locked, err := j.storage.Lock()
 	if err != nil {
 		return errgo.Mask(err)
 	}
 	defer locked.Close()
-	f, err := os.OpenFile(j.filename, os.O_RDWR|os.O_CREATE, 0600)
-	if err != nil {
-		return errgo.Mask(err)
-	}
-	defer f.Close()
-	// TODO optimization: if the file hasn't changed since we
-	// loaded it, don't bother with the merge step.
 
 	j.mu.Lock()
 	defer j.mu.Unlock()
-	if err := j.mergeFrom(f); err != nil {
-		// The cookie file is probably corrupt.
-		log.Printf("cannot read cookie file to merge it; ignoring it: %v", err)
+	data, stored, changed, err := j.loadChanged()
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	// loaded holds the cookie data that was loaded,
+	// if it has changed since we last saw it.
+	var loaded []byte
+	if changed {
+		loaded, err = j.decodeStored(data)
+		if err != nil {
+			// Don't overwrite cookies that we can't read.
+			return errgo.Mask(err)
+		}
+		if err := j.mergeFrom(bytes.NewReader(loaded)); err != nil {
+			// The cookie file is probably corrupt.
+			log.Printf("cannot read cookie file to merge it; ignoring it: %v", err)
+			// Make sure that it gets overwritten.
+			j.changed()
+		}
 	}
+	j.stored = stored
 	j.deleteExpired(now)
-	if err := f.Truncate(0); err != nil {
-		return errgo.Notef(err, "cannot truncate file")
+	j.evict(now)
+	if !j.dirty && !changed {
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := j.writeTo(&buf); err != nil {
+		return errgo.Mask(err)
+	}
+	if !j.dirty && bytes.Equal(buf.Bytes(), loaded) {
+		// The stored data changed, but only to
+		// cookies that we already hold.
+		return nil
+	}
+	data, err = j.encodeStored(buf.Bytes())
+	if err != nil {
+		return errgo.Notef(err, "cannot encrypt cookies")
 	}
-	if _, err := f.Seek(0, 0); err != nil {
+	if err := j.storage.Store(data); err != nil {
 		return errgo.Mask(err)
 	}
-	return j.writeTo(f)
+	j.dirty = false
+	j.stored = storedState{
+		known: true,
+		hash:  sha256.Sum256(data),
+	}
+	if vs, ok := j.storage.(versionedStorage); ok {
+		// If this fails, the next save will just load
+		// the data again.
+		j.stored.version, _ = vs.version()
+	}
+	return nil
 }
 
-// load loads the cookies from j.filename. If the file does not exist,
-// no error will be returned and no cookies will be loaded.
+// load loads the cookies from j.storage. If nothing has been
+// stored, no error will be returned and no cookies will be loaded.
 func (j *Jar) load() error {
-	if _, err := os.Stat(filepath.Dir(j.filename)); os.IsNotExist(err) {
-		// The directory that we'll store the cookie jar
-		// in doesn't exist, so don't bother trying
-		// to acquire the lock.
-		return nil
-	}
-	locked, err := lockFile(lockFileName(j.filename))
+	locked, err := j.storage.Lock()
 	if err != nil {
 		return errgo.Mask(err)
 	}
 	defer locked.Close()
-	f, err := os.Open(j.filename)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	data, stored, _, err := j.loadChanged()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil
-		}
-		return err
+		return errgo.Mask(err)
 	}
-	defer f.Close()
-	if err := j.mergeFrom(f); err != nil {
+	data, err = j.decodeStored(data)
+	if err != nil {
 		return errgo.Mask(err)
 	}
+	if err := j.mergeFrom(bytes.NewReader(data)); err != nil {
+		return errgo.Mask(err)
+	}
+	j.stored = stored
 	return nil
 }
 
+// storedState describes the data held in a jar's storage.
+type storedState struct {
+	// known holds whether the rest of the fields are valid.
+	known bool
+
+	// hash holds the SHA-256 hash of the data.
+	hash [sha256.Size]byte
+
+	// version holds the version of the data as reported by
+	// versionedStorage, or nil if that is not available.
+	version interface{}
+}
+
+// versionedStorage is implemented by Storage implementations that
+// can cheaply tell whether the stored data has changed without
+// reading it.
+type versionedStorage interface {
+	Storage
+
+	// version returns a comparable value that changes whenever the
+	// stored data changes. It returns nil if it cannot reliably
+	// tell.
+	version() (interface{}, error)
+}
+
+// loadChanged loads the data from j.storage and reports whether it
+// has changed since it was last loaded or saved. If the storage
+// implements versionedStorage and its version has not changed, the
+// data is not read at all and nil is returned. It also returns the
+// state of the loaded data, which the caller should store in
+// j.stored only once the data has been successfully merged, so that
+// data that cannot be read is never treated as seen. It must be
+// called with j.mu held and the storage locked.
+func (j *Jar) loadChanged() ([]byte, storedState, bool, error) {
+	var version interface{}
+	if vs, ok := j.storage.(versionedStorage); ok {
+		v, err := vs.version()
+		if err != nil {
+			return nil, storedState{}, false, errgo.Mask(err)
+		}
+		if v != nil && j.stored.known && v == j.stored.version {
+			return nil, j.stored, false, nil
+		}
+		version = v
+	}
+	data, err := j.storage.Load()
+	if err != nil {
+		return nil, storedState{}, false, errgo.Mask(err)
+	}
+	stored := storedState{
+		known:   true,
+		hash:    sha256.Sum256(data),
+		version: version,
+	}
+	changed := !j.stored.known || stored.hash != j.stored.hash
+	return data, stored, changed, nil
+}
+
 // mergeFrom reads all the cookies from r and stores them in the Jar.
 func (j *Jar) mergeFrom(r io.Reader) error {
 	decoder := json.NewDecoder(r)
@@ -131,13 +279,15 @@ func (j *Jar) writeTo(w io.Writer) error {
 	return nil
 }
 
-// allPersistentEntries returns all the entries in the jar, sorted by primarly by canonical host
-// name and secondarily by path length.
+// allPersistentEntries returns all the entries in the jar that should
+// be saved, sorted by primarly by canonical host name and secondarily
+// by path length. Session entries are included only if
+// Options.PersistSessionCookies was set.
 func (j *Jar) allPersistentEntries() []entry {
 	var entries []entry
 	for _, submap := range j.entries {
 		for _, e := range submap {
-			if e.Persistent {
+			if e.Persistent || j.persistSession {
 				entries = append(entries, e)
 			}
 		}
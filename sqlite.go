@@ -0,0 +1,532 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+
+	"gopkg.in/errgo.v1"
+)
+
+// This file implements just enough of the SQLite file format
+// (see https://www.sqlite.org/fileformat2.html) to read the tables
+// of the cookie databases used by browsers. The database is never
+// written or locked, so it can be read while a browser is using it.
+
+// sqliteDB holds a read-only SQLite database.
+type sqliteDB struct {
+	data []byte
+
+	// pageSize holds the size of each page in bytes.
+	pageSize int
+
+	// usableSize holds the size of each page
+	// excluding any reserved space at its end.
+	usableSize int
+
+	// walPages holds the most recent committed version
+	// of each page in the write-ahead log, if any.
+	walPages map[uint32][]byte
+}
+
+// sqliteTable holds the contents of a table in a SQLite database.
+type sqliteTable struct {
+	// columns holds the names of the columns in the table.
+	columns []string
+
+	// rows holds the rows of the table. Each value is nil,
+	// int64, float64, string or []byte.
+	rows [][]interface{}
+}
+
+// column returns the index of the column with the given name,
+// or -1 if there is no such column.
+func (t *sqliteTable) column(name string) int {
+	for i, c := range t.columns {
+		if strings.EqualFold(c, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+const sqliteMagic = "SQLite format 3\x00"
+
+// openSQLite reads the SQLite database in the given file, including
+// any changes in its write-ahead log that have not yet been
+// checkpointed into it.
+func openSQLite(filename string) (*sqliteDB, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errgo.Mask(err, os.IsNotExist)
+	}
+	db := &sqliteDB{
+		data: data,
+	}
+	wal, err := ioutil.ReadFile(filename + "-wal")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errgo.Mask(err)
+	}
+	if len(wal) > 0 {
+		db.readWAL(wal)
+	}
+	page1, err := db.page(1)
+	if err != nil {
+		return nil, errgo.Notef(err, "cannot read %q", filename)
+	}
+	if len(page1) < 100 || string(page1[:16]) != sqliteMagic {
+		return nil, errgo.Newf("%q is not a SQLite database", filename)
+	}
+	pageSize := int(binary.BigEndian.Uint16(page1[16:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, errgo.Newf("%q has invalid page size %d", filename, pageSize)
+	}
+	if db.pageSize != 0 && db.pageSize != pageSize {
+		return nil, errgo.Newf("%q has inconsistent page sizes", filename)
+	}
+	db.pageSize = pageSize
+	db.usableSize = pageSize - int(page1[20])
+	if db.usableSize < 480 {
+		// SQLite itself does not allow less.
+		return nil, errgo.Newf("%q has invalid reserved space size %d", filename, page1[20])
+	}
+	if enc := binary.BigEndian.Uint32(page1[56:]); enc != 0 && enc != 1 {
+		return nil, errgo.Newf("%q uses an unsupported text encoding", filename)
+	}
+	return db, nil
+}
+
+// readWAL reads the committed pages from the given write-ahead log
+// contents. Frames that are not part of a complete transaction, or
+// that were left over from an earlier generation of the log, are
+// ignored, as SQLite itself does.
+func (db *sqliteDB) readWAL(wal []byte) {
+	if len(wal) < 32 {
+		return
+	}
+	var order binary.ByteOrder
+	switch binary.BigEndian.Uint32(wal) {
+	case 0x377f0682:
+		order = binary.LittleEndian
+	case 0x377f0683:
+		order = binary.BigEndian
+	default:
+		return
+	}
+	pageSize := int(binary.BigEndian.Uint32(wal[8:]))
+	if pageSize < 512 || pageSize > 65536 || pageSize&(pageSize-1) != 0 {
+		return
+	}
+	s0, s1 := walChecksum(order, wal[:24], 0, 0)
+	if s0 != binary.BigEndian.Uint32(wal[24:]) || s1 != binary.BigEndian.Uint32(wal[28:]) {
+		return
+	}
+	salt := wal[16:24]
+	pages := make(map[uint32][]byte)
+	committed := make(map[uint32][]byte)
+	for off := 32; off+24+pageSize <= len(wal); off += 24 + pageSize {
+		header := wal[off : off+24]
+		if string(header[8:16]) != string(salt) {
+			break
+		}
+		s0, s1 = walChecksum(order, header[:8], s0, s1)
+		page := wal[off+24 : off+24+pageSize]
+		s0, s1 = walChecksum(order, page, s0, s1)
+		if s0 != binary.BigEndian.Uint32(header[16:]) || s1 != binary.BigEndian.Uint32(header[20:]) {
+			break
+		}
+		pages[binary.BigEndian.Uint32(header)] = page
+		if binary.BigEndian.Uint32(header[4:]) != 0 {
+			// A commit frame: all the frames so
+			// far make up complete transactions.
+			for n, p := range pages {
+				committed[n] = p
+			}
+		}
+	}
+	if len(committed) > 0 {
+		db.pageSize = pageSize
+		db.walPages = committed
+	}
+}
+
+// walChecksum computes the checksum used in write-ahead logs
+// over data, starting from s0 and s1.
+func walChecksum(order binary.ByteOrder, data []byte, s0, s1 uint32) (uint32, uint32) {
+	for i := 0; i+8 <= len(data); i += 8 {
+		s0 += order.Uint32(data[i:]) + s1
+		s1 += order.Uint32(data[i+4:]) + s0
+	}
+	return s0, s1
+}
+
+// page returns the contents of the page with the given number.
+// Pages are numbered from 1.
+func (db *sqliteDB) page(n uint32) ([]byte, error) {
+	if p, ok := db.walPages[n]; ok {
+		return p, nil
+	}
+	pageSize := db.pageSize
+	if pageSize == 0 {
+		// We're reading the first page to find out
+		// the page size; the header is all we need.
+		pageSize = 100
+	}
+	start := int64(n-1) * int64(pageSize)
+	if n == 0 || start+int64(pageSize) > int64(len(db.data)) {
+		return nil, errgo.Newf("page %d out of range", n)
+	}
+	return db.data[start : start+int64(pageSize)], nil
+}
+
+// table returns the contents of the table with the given name.
+func (db *sqliteDB) table(name string) (*sqliteTable, error) {
+	var master [][]interface{}
+	if err := db.readTable(1, &master); err != nil {
+		return nil, errgo.Notef(err, "cannot read schema")
+	}
+	for _, row := range master {
+		if len(row) < 5 || row[0] != "table" {
+			continue
+		}
+		if tableName, _ := row[1].(string); !strings.EqualFold(tableName, name) {
+			continue
+		}
+		root, _ := row[3].(int64)
+		sql, _ := row[4].(string)
+		columns, rowidColumn := parseCreateTable(sql)
+		if columns == nil {
+			return nil, errgo.Newf("cannot parse schema of table %q", name)
+		}
+		t := &sqliteTable{
+			columns: columns,
+		}
+		if err := db.readTable(uint32(root), &t.rows); err != nil {
+			return nil, errgo.Notef(err, "cannot read table %q", name)
+		}
+		for i, row := range t.rows {
+			// The rowid is stored in place of the last
+			// value in each row by readTable.
+			rowid := row[len(row)-1]
+			row = row[:len(row)-1]
+			for len(row) < len(columns) {
+				// Columns added by ALTER TABLE are missing
+				// from rows that were written before.
+				row = append(row, nil)
+			}
+			if rowidColumn >= 0 {
+				row[rowidColumn] = rowid
+			}
+			t.rows[i] = row[:len(columns)]
+		}
+		return t, nil
+	}
+	return nil, errgo.Newf("no table %q found", name)
+}
+
+// readTable appends the rows of the table b-tree with the given root
+// page to rows. The rowid of each row is appended after its values.
+func (db *sqliteDB) readTable(root uint32, rows *[][]interface{}) error {
+	return db.readTablePage(root, rows, 0)
+}
+
+// maxBTreeDepth limits the depth of b-tree that we will read,
+// to guard against loops in corrupt databases.
+const maxBTreeDepth = 32
+
+func (db *sqliteDB) readTablePage(n uint32, rows *[][]interface{}, depth int) error {
+	if depth > maxBTreeDepth {
+		return errgo.New("b-tree too deep")
+	}
+	page, err := db.page(n)
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	hdr := 0
+	if n == 1 {
+		hdr = 100
+	}
+	if len(page) < hdr+8 {
+		return errgo.Newf("page %d too short", n)
+	}
+	kind := page[hdr]
+	ncells := int(binary.BigEndian.Uint16(page[hdr+3:]))
+	var cellPointers []byte
+	switch kind {
+	case 0x0d:
+		cellPointers = page[hdr+8:]
+	case 0x05:
+		if len(page) < hdr+12 {
+			return errgo.Newf("page %d too short", n)
+		}
+		cellPointers = page[hdr+12:]
+	default:
+		return errgo.Newf("page %d is not a table b-tree page", n)
+	}
+	if len(cellPointers) < 2*ncells {
+		return errgo.Newf("page %d has too many cells", n)
+	}
+	for i := 0; i < ncells; i++ {
+		off := int(binary.BigEndian.Uint16(cellPointers[2*i:]))
+		if off >= len(page) {
+			return errgo.Newf("page %d has invalid cell pointer", n)
+		}
+		cell := page[off:]
+		if kind == 0x05 {
+			if len(cell) < 4 {
+				return errgo.Newf("page %d has truncated cell", n)
+			}
+			if err := db.readTablePage(binary.BigEndian.Uint32(cell), rows, depth+1); err != nil {
+				return errgo.Mask(err)
+			}
+			continue
+		}
+		row, err := db.readLeafCell(cell)
+		if err != nil {
+			return errgo.Notef(err, "page %d", n)
+		}
+		*rows = append(*rows, row)
+	}
+	if kind == 0x05 {
+		right := binary.BigEndian.Uint32(page[hdr+8:])
+		return errgo.Mask(db.readTablePage(right, rows, depth+1))
+	}
+	return nil
+}
+
+// readLeafCell reads the record in a table b-tree leaf cell and
+// returns its values followed by its rowid.
+func (db *sqliteDB) readLeafCell(cell []byte) ([]interface{}, error) {
+	size, n := sqliteVarint(cell)
+	if n == 0 {
+		return nil, errgo.New("truncated cell")
+	}
+	cell = cell[n:]
+	rowid, n := sqliteVarint(cell)
+	if n == 0 {
+		return nil, errgo.New("truncated cell")
+	}
+	cell = cell[n:]
+	payload, err := db.payload(cell, int(size))
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	values, err := parseRecord(payload)
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	return append(values, int64(rowid)), nil
+}
+
+// payload returns the payload of a table leaf cell of the given total
+// size, following the chain of overflow pages if necessary.
+func (db *sqliteDB) payload(cell []byte, size int) ([]byte, error) {
+	u := db.usableSize
+	if u < 480 {
+		return nil, errgo.Newf("invalid usable page size %d", u)
+	}
+	if size < 0 || int64(size) > int64(len(db.data))+int64(len(db.walPages)*db.pageSize) {
+		// The payload cannot be larger than the database.
+		return nil, errgo.Newf("invalid payload size %d", size)
+	}
+	local := size
+	if max := u - 35; size > max {
+		min := (u-12)*32/255 - 23
+		local = min + (size-min)%(u-4)
+		if local > max {
+			local = min
+		}
+	}
+	if len(cell) < local {
+		return nil, errgo.New("truncated cell")
+	}
+	if local == size {
+		return cell[:size], nil
+	}
+	if len(cell) < local+4 {
+		return nil, errgo.New("truncated cell")
+	}
+	payload := make([]byte, 0, size)
+	payload = append(payload, cell[:local]...)
+	next := binary.BigEndian.Uint32(cell[local:])
+	for len(payload) < size {
+		if next == 0 {
+			return nil, errgo.New("overflow chain too short")
+		}
+		page, err := db.page(next)
+		if err != nil {
+			return nil, errgo.Mask(err)
+		}
+		if len(page) < u {
+			return nil, errgo.New("overflow page too short")
+		}
+		next = binary.BigEndian.Uint32(page)
+		chunk := page[4:u]
+		if n := size - len(payload); len(chunk) > n {
+			chunk = chunk[:n]
+		}
+		payload = append(payload, chunk...)
+	}
+	return payload, nil
+}
+
+// parseRecord parses the values in a record.
+func parseRecord(record []byte) ([]interface{}, error) {
+	headerSize, n := sqliteVarint(record)
+	if n == 0 || headerSize < uint64(n) || headerSize > uint64(len(record)) {
+		return nil, errgo.New("invalid record header")
+	}
+	header := record[n:headerSize]
+	body := record[headerSize:]
+	var values []interface{}
+	for len(header) > 0 {
+		serialType, n := sqliteVarint(header)
+		if n == 0 {
+			return nil, errgo.New("invalid record header")
+		}
+		header = header[n:]
+		size := serialTypeSize(serialType)
+		if size > uint64(len(body)) {
+			return nil, errgo.New("truncated record")
+		}
+		data := body[:size]
+		body = body[size:]
+		switch {
+		case serialType == 0:
+			values = append(values, nil)
+		case serialType <= 6:
+			values = append(values, sqliteInt(data))
+		case serialType == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(data)))
+		case serialType == 8:
+			values = append(values, int64(0))
+		case serialType == 9:
+			values = append(values, int64(1))
+		case serialType >= 12 && serialType%2 == 0:
+			values = append(values, append([]byte(nil), data...))
+		case serialType >= 13:
+			values = append(values, string(data))
+		default:
+			return nil, errgo.Newf("invalid serial type %d", serialType)
+		}
+	}
+	return values, nil
+}
+
+// serialTypeSize returns the size of the data
+// for a value with the given serial type.
+func serialTypeSize(t uint64) uint64 {
+	switch t {
+	case 0, 8, 9, 10, 11:
+		return 0
+	case 1, 2, 3, 4:
+		return t
+	case 5:
+		return 6
+	case 6, 7:
+		return 8
+	}
+	return (t - 12) / 2
+}
+
+// sqliteInt returns the big-endian two's complement
+// integer held in data.
+func sqliteInt(data []byte) int64 {
+	var v int64
+	if len(data) > 0 && data[0]&0x80 != 0 {
+		v = -1
+	}
+	for _, b := range data {
+		v = v<<8 | int64(b)
+	}
+	return v
+}
+
+// sqliteVarint decodes a SQLite variable-length integer from the start
+// of data and returns it along with the number of bytes it occupies,
+// which is zero if data is too short.
+func sqliteVarint(data []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(data) {
+			return 0, 0
+		}
+		if i == 8 {
+			return v<<8 | uint64(data[i]), 9
+		}
+		v = v<<7 | uint64(data[i]&0x7f)
+		if data[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	panic("unreachable")
+}
+
+// parseCreateTable returns the names of the columns defined by the
+// given CREATE TABLE statement, and the index of the column that is
+// an alias for the rowid, or -1 if there is none. It returns nil
+// columns if the statement cannot be parsed.
+func parseCreateTable(sql string) ([]string, int) {
+	start := strings.IndexByte(sql, '(')
+	end := strings.LastIndexByte(sql, ')')
+	if start < 0 || end < start {
+		return nil, -1
+	}
+	var columns []string
+	rowidColumn := -1
+	for _, def := range splitTopLevel(sql[start+1 : end]) {
+		fields := strings.Fields(def)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+			// A table constraint rather than a column.
+			continue
+		}
+		if len(fields) >= 4 && strings.EqualFold(fields[1], "INTEGER") && strings.EqualFold(fields[2], "PRIMARY") && strings.EqualFold(fields[3], "KEY") {
+			rowidColumn = len(columns)
+		}
+		columns = append(columns, strings.Trim(fields[0], "\"`[]"))
+	}
+	return columns, rowidColumn
+}
+
+// splitTopLevel splits s at commas that are not inside
+// parentheses or quotes.
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth := 0
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '[':
+			quote = ']'
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
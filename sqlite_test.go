@@ -0,0 +1,223 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	"gopkg.in/errgo.v1"
+)
+
+var sqliteVarintTests = []struct {
+	data []byte
+	want uint64
+	n    int
+}{
+	{[]byte{0x00}, 0, 1},
+	{[]byte{0x7f}, 0x7f, 1},
+	{[]byte{0x81, 0x00}, 0x80, 2},
+	{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 0xffffffffffffffff, 9},
+	{[]byte{0x81}, 0, 0},
+}
+
+func TestSQLiteVarint(t *testing.T) {
+	for _, test := range sqliteVarintTests {
+		got, n := sqliteVarint(test.data)
+		if got != test.want || n != test.n {
+			t.Errorf("sqliteVarint(%x) = %d, %d; want %d, %d", test.data, got, n, test.want, test.n)
+		}
+	}
+}
+
+var parseCreateTableTests = []struct {
+	sql   string
+	want  string
+	rowid int
+}{{
+	sql:   "CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT, b)",
+	want:  "id a b",
+	rowid: 0,
+}, {
+	sql:   `CREATE TABLE "t" ("a" TEXT DEFAULT 'x,y', [b] INTEGER CHECK (b IN (1, 2)), c, CONSTRAINT u UNIQUE (a, c))`,
+	want:  "a b c",
+	rowid: -1,
+}, {
+	sql:   "CREATE TABLE t (a, b, PRIMARY KEY (a, b)) WITHOUT ROWID",
+	want:  "a b",
+	rowid: -1,
+}}
+
+func TestParseCreateTable(t *testing.T) {
+	for _, test := range parseCreateTableTests {
+		columns, rowid := parseCreateTable(test.sql)
+		if got := strings.Join(columns, " "); got != test.want || rowid != test.rowid {
+			t.Errorf("parseCreateTable(%q) = %q, %d; want %q, %d", test.sql, got, rowid, test.want, test.rowid)
+		}
+	}
+}
+
+func TestSQLiteTable(t *testing.T) {
+	c := qt.New(t)
+	db, err := openSQLite("testdata/firefox/cookies.sqlite")
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(db.pageSize, qt.Equals, 1024)
+	table, err := db.table("moz_cookies")
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(len(table.columns), qt.Equals, 16)
+	c.Assert(len(table.rows), qt.Equals, 87)
+	id, name, value, rawSameSite := table.column("id"), table.column("name"), table.column("value"), table.column("rawSameSite")
+	for i, row := range table.rows {
+		// The id column is an alias for the rowid.
+		c.Assert(row[id], qt.Equals, int64(i+1))
+		if i < 80 {
+			// Rows written before a column was
+			// added have no value for it.
+			c.Assert(row[rawSameSite], qt.Equals, nil)
+		} else {
+			c.Assert(row[rawSameSite], qt.Equals, int64(0))
+		}
+	}
+	// The long value is stored in overflow pages.
+	c.Assert(table.rows[86][name], qt.Equals, "long")
+	c.Assert(table.rows[86][value], qt.Equals, strings.Repeat("x", 3000))
+
+	_, err = db.table("nonexistent")
+	c.Assert(err, qt.ErrorMatches, `no table "nonexistent" found`)
+}
+
+func TestSQLiteWAL(t *testing.T) {
+	c := qt.New(t)
+	db, err := openSQLite("testdata/firefox-wal/cookies.sqlite")
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(sqliteColumn(c, db, "moz_cookies", "value"), qt.Equals, "2 3")
+}
+
+func TestSQLiteWALIncomplete(t *testing.T) {
+	c := qt.New(t)
+	dir, err := ioutil.TempDir("", "cookiejar-test")
+	c.Assert(err, qt.Equals, nil)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"cookies.sqlite", "cookies.sqlite-wal"} {
+		data, err := ioutil.ReadFile(filepath.Join("testdata/firefox-wal", name))
+		c.Assert(err, qt.Equals, nil)
+		if name == "cookies.sqlite-wal" {
+			// Remove the end of the last frame so that
+			// the last transaction is incomplete.
+			data = data[:len(data)-10]
+		}
+		err = ioutil.WriteFile(filepath.Join(dir, name), data, 0600)
+		c.Assert(err, qt.Equals, nil)
+	}
+	db, err := openSQLite(filepath.Join(dir, "cookies.sqlite"))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(sqliteColumn(c, db, "moz_cookies", "value"), qt.Equals, "2")
+
+	// Without the log, only the checkpointed data is seen.
+	err = os.Remove(filepath.Join(dir, "cookies.sqlite-wal"))
+	c.Assert(err, qt.Equals, nil)
+	db, err = openSQLite(filepath.Join(dir, "cookies.sqlite"))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(sqliteColumn(c, db, "moz_cookies", "value"), qt.Equals, "1")
+}
+
+func TestOpenSQLiteNotDatabase(t *testing.T) {
+	c := qt.New(t)
+	dir, err := ioutil.TempDir("", "cookiejar-test")
+	c.Assert(err, qt.Equals, nil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cookies.sqlite")
+	err = ioutil.WriteFile(path, []byte(strings.Repeat("not a database ", 10)), 0600)
+	c.Assert(err, qt.Equals, nil)
+	_, err = openSQLite(path)
+	c.Assert(err, qt.ErrorMatches, `".*cookies.sqlite" is not a SQLite database`)
+
+	_, err = openSQLite(filepath.Join(dir, "nonexistent"))
+	c.Assert(os.IsNotExist(errgo.Cause(err)), qt.Equals, true)
+}
+
+var parseRecordErrorTests = []struct {
+	about  string
+	record []byte
+	expect string
+}{{
+	about:  "empty record",
+	record: nil,
+	expect: "invalid record header",
+}, {
+	about:  "header size too small",
+	record: []byte{0},
+	expect: "invalid record header",
+}, {
+	about:  "header size too large",
+	record: []byte{3, 1},
+	expect: "invalid record header",
+}, {
+	about:  "truncated serial type",
+	record: []byte{2, 0x81},
+	expect: "invalid record header",
+}, {
+	about:  "truncated value",
+	record: []byte{2, 2, 0},
+	expect: "truncated record",
+}, {
+	about:  "reserved serial type",
+	record: []byte{2, 10},
+	expect: "invalid serial type 10",
+}}
+
+func TestParseRecordError(t *testing.T) {
+	for _, test := range parseRecordErrorTests {
+		_, err := parseRecord(test.record)
+		if err == nil || err.Error() != test.expect {
+			t.Errorf("%s: got error %v want %q", test.about, err, test.expect)
+		}
+	}
+}
+
+func TestSQLiteCorrupt(t *testing.T) {
+	c := qt.New(t)
+	data, err := ioutil.ReadFile("testdata/firefox/cookies.sqlite")
+	c.Assert(err, qt.Equals, nil)
+	dir, err := ioutil.TempDir("", "cookiejar-test")
+	c.Assert(err, qt.Equals, nil)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cookies.sqlite")
+
+	// Corrupt a few random bytes at a time. Reading the
+	// database may or may not succeed, but must not panic.
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		corrupt := append([]byte(nil), data...)
+		for j := r.Intn(4); j >= 0; j-- {
+			corrupt[r.Intn(len(corrupt))] = byte(r.Intn(256))
+		}
+		err := ioutil.WriteFile(path, corrupt, 0600)
+		c.Assert(err, qt.Equals, nil)
+		db, err := openSQLite(path)
+		if err != nil {
+			continue
+		}
+		db.table("moz_cookies")
+	}
+}
+
+// sqliteColumn returns the values in the given column
+// of the given table, separated by spaces.
+func sqliteColumn(c *qt.C, db *sqliteDB, table, column string) string {
+	t, err := db.table(table)
+	c.Assert(err, qt.Equals, nil)
+	i := t.column(column)
+	var values []string
+	for _, row := range t.rows {
+		values = append(values, sqliteString(row[i]))
+	}
+	return strings.Join(values, " ")
+}
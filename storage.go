@@ -0,0 +1,200 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/errgo.v1"
+)
+
+// Storage is the interface used by a Jar to persist its cookies.
+//
+// The data held in a Storage is the serialized form of the jar's
+// cookies (see Jar.MarshalJSON), encrypted if Options.Encryption is
+// set; a Storage does not need to interpret it. When saving, the jar
+// locks the storage, loads the data, merges it with its own cookies
+// and stores the result, so a single Storage may be shared by several
+// jars, possibly in different processes.
+type Storage interface {
+	// Lock acquires an exclusive lock on the storage and
+	// returns a Closer that releases it.
+	Lock() (io.Closer, error)
+
+	// Load returns the most recently stored data.
+	// If nothing has been stored, it returns no data
+	// and no error.
+	Load() ([]byte, error)
+
+	// Store replaces the stored data with the given data.
+	Store(data []byte) error
+}
+
+// NewFileStorage returns a Storage that keeps the cookies in the
+// named file. This is the Storage used by New unless
+// Options.Storage is set.
+//
+// Access to the file is serialized with a lock file
+// whose name is the file name with ".lock" appended.
+func NewFileStorage(filename string) Storage {
+	return &fileStorage{
+		filename: filename,
+	}
+}
+
+// fileStorage implements Storage by storing the cookies in a file.
+type fileStorage struct {
+	filename string
+}
+
+// Lock implements Storage.Lock.
+func (s *fileStorage) Lock() (io.Closer, error) {
+	if _, err := os.Stat(filepath.Dir(s.filename)); os.IsNotExist(err) {
+		// The directory that we'll store the cookie jar
+		// in doesn't exist, so don't bother trying
+		// to acquire the lock.
+		return nopCloser{}, nil
+	}
+	return lockFile(lockFileName(s.filename))
+}
+
+// Load implements Storage.Load.
+func (s *fileStorage) Load() ([]byte, error) {
+	data, err := ioutil.ReadFile(s.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// Store implements Storage.Store. The data is written to a temporary
+// file in the same directory, which is then renamed over the cookie
+// file, so that a crash part way through never leaves the cookie file
+// empty or partially written. If the cookie file is a symbolic link,
+// the file that it refers to is replaced instead.
+func (s *fileStorage) Store(data []byte) (err error) {
+	filename, err := followSymlinks(s.filename)
+	if err != nil {
+		return errgo.Notef(err, "cannot resolve cookie file")
+	}
+	dir := filepath.Dir(filename)
+	f, err := ioutil.TempFile(dir, filepath.Base(filename)+".tmp")
+	if err != nil {
+		return errgo.Notef(err, "cannot create temporary file")
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	// TempFile creates the file with mode 0600 but
+	// make sure of it regardless of the umask.
+	if err := f.Chmod(0600); err != nil {
+		return errgo.Mask(err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return errgo.Mask(err)
+	}
+	if err := f.Sync(); err != nil {
+		return errgo.Mask(err)
+	}
+	if err := f.Close(); err != nil {
+		return errgo.Mask(err)
+	}
+	if err := os.Rename(f.Name(), filename); err != nil {
+		return errgo.Notef(err, "cannot replace cookie file")
+	}
+	syncDir(dir)
+	return nil
+}
+
+// maxSymlinks holds the maximum number of symbolic links
+// that followSymlinks will follow.
+const maxSymlinks = 40
+
+// followSymlinks returns the name of the file that the named file
+// refers to if it is a symbolic link, following any further links.
+// Unlike filepath.EvalSymlinks, it succeeds when the final file does
+// not exist, so that a link to a cookie file that has not been created
+// yet is kept too.
+func followSymlinks(name string) (string, error) {
+	for i := 0; i < maxSymlinks; i++ {
+		info, err := os.Lstat(name)
+		if os.IsNotExist(err) || err == nil && info.Mode()&os.ModeSymlink == 0 {
+			return name, nil
+		}
+		if err != nil {
+			return "", errgo.Mask(err)
+		}
+		link, err := os.Readlink(name)
+		if err != nil {
+			return "", errgo.Mask(err)
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(name), link)
+		}
+		name = link
+	}
+	return "", errgo.Newf("too many symbolic links in %q", name)
+}
+
+// syncDir flushes the named directory to disk, so that
+// a file renamed into it survives a crash. Not all systems
+// can sync directories, so errors are ignored.
+func syncDir(dir string) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	f.Sync()
+	f.Close()
+}
+
+// fileVersion is the version of a file as returned by
+// fileStorage.version.
+type fileVersion struct {
+	size  int64
+	mtime int64
+}
+
+// racyDuration holds the time for which a file's modification time
+// is not trusted to tell whether it has changed, because file systems
+// may store modification times with coarse granularity.
+var racyDuration = 2 * time.Second
+
+// version implements versionedStorage.version by returning the size
+// and modification time of the file.
+func (s *fileStorage) version() (interface{}, error) {
+	info, err := os.Stat(s.filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fileVersion{}, nil
+		}
+		return nil, errgo.Mask(err)
+	}
+	if time.Since(info.ModTime()) < racyDuration {
+		// The file might be modified again without
+		// its modification time changing.
+		return nil, nil
+	}
+	return fileVersion{
+		size:  info.Size(),
+		mtime: info.ModTime().UnixNano(),
+	}, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error {
+	return nil
+}
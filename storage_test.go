@@ -0,0 +1,544 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"gopkg.in/errgo.v1"
+)
+
+// memStorage implements Storage in memory.
+type memStorage struct {
+	lock sync.Mutex
+
+	mu     sync.Mutex
+	data   []byte
+	loads  int
+	stores int
+}
+
+func (s *memStorage) Lock() (io.Closer, error) {
+	s.lock.Lock()
+	return unlocker{&s.lock}, nil
+}
+
+func (s *memStorage) Load() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loads++
+	return s.data, nil
+}
+
+func (s *memStorage) Store(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = append([]byte(nil), data...)
+	s.stores++
+	return nil
+}
+
+type unlocker struct {
+	mu *sync.Mutex
+}
+
+func (u unlocker) Close() error {
+	u.mu.Unlock()
+	return nil
+}
+
+func newStorageJar(c *qt.C, storage Storage) *Jar {
+	jar, err := New(&Options{
+		PublicSuffixList: testPSL{},
+		Storage:          storage,
+	})
+	c.Assert(err, qt.Equals, nil)
+	return jar
+}
+
+func TestStorage(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	j0 := newStorageJar(c, storage)
+	j1 := newStorageJar(c, storage)
+	now := time.Now()
+	setCookies(j0, "http://www.host.test", []string{"a=a; max-age=100"}, now)
+	setCookies(j1, "http://www.host.test", []string{"b=b; max-age=100"}, now)
+	c.Assert(j0.Save(), qt.Equals, nil)
+	c.Assert(j1.Save(), qt.Equals, nil)
+	c.Assert(storage.stores, qt.Equals, 2)
+	c.Assert(allCookies(j1, now), qt.Equals, "a=a b=b")
+
+	j2 := newStorageJar(c, storage)
+	c.Assert(j2.entries, qt.DeepEquals, j1.entries)
+}
+
+func TestStorageWithNoPersist(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	jar, err := New(&Options{
+		PublicSuffixList: testPSL{},
+		Storage:          storage,
+		NoPersist:        true,
+	})
+	c.Assert(err, qt.Equals, nil)
+	setCookies(jar, "http://www.host.test", []string{"a=a; max-age=100"}, time.Now())
+	c.Assert(jar.Save(), qt.Equals, nil)
+	c.Assert(storage.stores, qt.Equals, 0)
+}
+
+func TestFileStorageStore(t *testing.T) {
+	c := qt.New(t)
+	d, err := ioutil.TempDir("", "")
+	c.Assert(err, qt.Equals, nil)
+	defer os.RemoveAll(d)
+	file := filepath.Join(d, "cookies")
+	err = ioutil.WriteFile(file, []byte("old"), 0644)
+	c.Assert(err, qt.Equals, nil)
+
+	s := NewFileStorage(file)
+	err = s.Store([]byte("new"))
+	c.Assert(err, qt.Equals, nil)
+	data, err := s.Load()
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(string(data), qt.Equals, "new")
+	info, err := os.Stat(file)
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(info.Mode().Perm(), qt.Equals, os.FileMode(0600))
+
+	// No temporary files should be left behind.
+	names, err := filepath.Glob(filepath.Join(d, "*.tmp*"))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(len(names), qt.Equals, 0)
+}
+
+func TestFileStorageStoreSymlink(t *testing.T) {
+	c := qt.New(t)
+	d, err := ioutil.TempDir("", "")
+	c.Assert(err, qt.Equals, nil)
+	defer os.RemoveAll(d)
+	err = os.Mkdir(filepath.Join(d, "dotfiles"), 0700)
+	c.Assert(err, qt.Equals, nil)
+	target := filepath.Join(d, "dotfiles", "cookies")
+	file := filepath.Join(d, "cookies")
+	// The link is relative, and refers to a file
+	// that does not exist yet.
+	err = os.Symlink(filepath.Join("dotfiles", "cookies"), file)
+	c.Assert(err, qt.Equals, nil)
+
+	s := NewFileStorage(file)
+	for _, data := range []string{"old", "new"} {
+		err = s.Store([]byte(data))
+		c.Assert(err, qt.Equals, nil)
+		info, err := os.Lstat(file)
+		c.Assert(err, qt.Equals, nil)
+		c.Assert(info.Mode()&os.ModeSymlink, qt.Equals, os.ModeSymlink)
+		stored, err := ioutil.ReadFile(target)
+		c.Assert(err, qt.Equals, nil)
+		c.Assert(string(stored), qt.Equals, data)
+	}
+	names, err := filepath.Glob(filepath.Join(d, "dotfiles", "*.tmp*"))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(len(names), qt.Equals, 0)
+}
+
+func TestFileStorageStoreFailure(t *testing.T) {
+	c := qt.New(t)
+	d, err := ioutil.TempDir("", "")
+	c.Assert(err, qt.Equals, nil)
+	defer os.RemoveAll(d)
+	// Make the cookie file a non-empty directory so that
+	// the final rename fails.
+	file := filepath.Join(d, "cookies")
+	err = os.MkdirAll(filepath.Join(file, "x"), 0700)
+	c.Assert(err, qt.Equals, nil)
+
+	err = NewFileStorage(file).Store([]byte("new"))
+	c.Assert(err, qt.ErrorMatches, "cannot replace cookie file: .*")
+	names, err := filepath.Glob(filepath.Join(d, "*.tmp*"))
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(len(names), qt.Equals, 0)
+}
+
+func TestSaveUnchanged(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	j0 := newStorageJar(c, storage)
+	c.Assert(j0.Save(), qt.Equals, nil)
+	c.Assert(storage.stores, qt.Equals, 0)
+
+	now := time.Now()
+	setCookies(j0, "http://www.host.test", []string{"a=a; max-age=100"}, now)
+	c.Assert(j0.Save(), qt.Equals, nil)
+	c.Assert(storage.stores, qt.Equals, 1)
+	c.Assert(j0.Save(), qt.Equals, nil)
+	c.Assert(storage.stores, qt.Equals, 1)
+
+	// Changes made by another jar are merged in
+	// even when there is nothing to write.
+	j1 := newStorageJar(c, storage)
+	setCookies(j1, "http://www.host.test", []string{"b=b; max-age=100"}, now)
+	c.Assert(j1.Save(), qt.Equals, nil)
+	c.Assert(storage.stores, qt.Equals, 2)
+	c.Assert(j0.Save(), qt.Equals, nil)
+	c.Assert(storage.stores, qt.Equals, 2)
+	c.Assert(allCookies(j0, now), qt.Equals, "a=a b=b")
+
+	// Removing a cookie causes a write.
+	j0.RemoveAll()
+	c.Assert(j0.Save(), qt.Equals, nil)
+	c.Assert(storage.stores, qt.Equals, 3)
+}
+
+func TestSaveCorruptStorage(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	j := newStorageJar(c, storage)
+	storage.data = []byte("[")
+	c.Assert(j.Save(), qt.Equals, nil)
+	c.Assert(string(storage.data), qt.Equals, "null\n")
+}
+
+// loadCountingStorage wraps fileStorage, counting
+// the calls to Load.
+type loadCountingStorage struct {
+	*fileStorage
+	loads int
+}
+
+func (s *loadCountingStorage) Load() ([]byte, error) {
+	s.loads++
+	return s.fileStorage.Load()
+}
+
+func TestSaveUnchangedFile(t *testing.T) {
+	c := qt.New(t)
+	defer patchRacyDuration(0)()
+	d, err := ioutil.TempDir("", "")
+	c.Assert(err, qt.Equals, nil)
+	defer os.RemoveAll(d)
+	file := filepath.Join(d, "cookies")
+	storage := &loadCountingStorage{
+		fileStorage: NewFileStorage(file).(*fileStorage),
+	}
+	j := newStorageJar(c, storage)
+	c.Assert(storage.loads, qt.Equals, 1)
+	setCookies(j, "http://www.host.test", []string{"a=a; max-age=100"}, time.Now())
+	// The file still does not exist so there is no need
+	// to read it.
+	c.Assert(j.Save(), qt.Equals, nil)
+	c.Assert(storage.loads, qt.Equals, 1)
+
+	// The file has not changed so it should not be read.
+	c.Assert(j.Save(), qt.Equals, nil)
+	c.Assert(storage.loads, qt.Equals, 1)
+
+	// Touching the file causes it to be read but the
+	// content is the same so nothing is written.
+	info, err := os.Stat(file)
+	c.Assert(err, qt.Equals, nil)
+	mtime := info.ModTime().Add(time.Second)
+	err = os.Chtimes(file, mtime, mtime)
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(j.Save(), qt.Equals, nil)
+	c.Assert(storage.loads, qt.Equals, 2)
+	info, err = os.Stat(file)
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(info.ModTime().Equal(mtime), qt.Equals, true)
+
+	// When another jar changes the file, it is read again.
+	j1 := newTestJar(file)
+	setCookies(j1, "http://www.host.test", []string{"b=b; max-age=100"}, time.Now())
+	c.Assert(j1.Save(), qt.Equals, nil)
+	c.Assert(j.Save(), qt.Equals, nil)
+	c.Assert(storage.loads, qt.Equals, 3)
+	c.Assert(allCookies(j, time.Now()), qt.Equals, "a=a b=b")
+}
+
+func TestSaveRecreatesRemovedFile(t *testing.T) {
+	c := qt.New(t)
+	d, err := ioutil.TempDir("", "")
+	c.Assert(err, qt.Equals, nil)
+	defer os.RemoveAll(d)
+	file := filepath.Join(d, "cookies")
+	j := newTestJar(file)
+	now := time.Now()
+	setCookies(j, "http://www.host.test", []string{"a=a; max-age=100"}, now)
+	c.Assert(j.Save(), qt.Equals, nil)
+
+	// Although nothing in the jar has changed, the stored
+	// cookies have, so the jar is saved again.
+	err = os.Remove(file)
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(j.Save(), qt.Equals, nil)
+	j1 := newTestJar(file)
+	c.Assert(allCookies(j1, now), qt.Equals, "a=a")
+}
+
+func TestSaveLastAccess(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	j0 := newStorageJar(c, storage)
+	now := time.Now()
+	setCookies(j0, "http://www.host.test", []string{"a=a; max-age=10000"}, now)
+	c.Assert(j0.Save(), qt.Equals, nil)
+	c.Assert(storage.stores, qt.Equals, 1)
+
+	// Reading a cookie soon after it was last accessed
+	// does not cause a save.
+	c.Assert(queryJar(j0, "http://www.host.test", now.Add(time.Second)), qt.Equals, "a=a")
+	c.Assert(j0.Save(), qt.Equals, nil)
+	c.Assert(storage.stores, qt.Equals, 1)
+
+	accessed := now.Add(time.Second + lastAccessResolution)
+	c.Assert(queryJar(j0, "http://www.host.test", accessed), qt.Equals, "a=a")
+	c.Assert(j0.Save(), qt.Equals, nil)
+	c.Assert(storage.stores, qt.Equals, 2)
+	j1 := newStorageJar(c, storage)
+	entries := j1.allEntries(false, accessed)
+	c.Assert(len(entries), qt.Equals, 1)
+	c.Assert(entries[0].LastAccess.Equal(accessed), qt.Equals, true)
+}
+
+func patchRacyDuration(d time.Duration) func() {
+	old := racyDuration
+	racyDuration = d
+	return func() {
+		racyDuration = old
+	}
+}
+
+func (s *memStorage) storeCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stores
+}
+
+func TestAutoSave(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	jar, err := New(&Options{
+		PublicSuffixList: testPSL{},
+		Storage:          storage,
+		AutoSaveDelay:    10 * time.Millisecond,
+	})
+	c.Assert(err, qt.Equals, nil)
+	u := mustParseURL("http://www.host.test")
+	for i := 0; i < 10; i++ {
+		jar.SetCookies(u, []*http.Cookie{{Name: fmt.Sprint("a", i), Value: "a", MaxAge: 100}})
+	}
+	for a := time.Now(); storage.storeCount() == 0; time.Sleep(time.Millisecond) {
+		if time.Since(a) > 5*time.Second {
+			c.Fatalf("timed out waiting for automatic save")
+		}
+	}
+	// Give any extra saves a chance to happen.
+	time.Sleep(50 * time.Millisecond)
+	c.Assert(storage.storeCount(), qt.Equals, 1)
+
+	// Close saves any outstanding changes immediately.
+	jar.RemoveAll()
+	c.Assert(jar.Close(), qt.Equals, nil)
+	c.Assert(storage.storeCount(), qt.Equals, 2)
+
+	// Changes after Close are not saved automatically.
+	jar.SetCookies(u, []*http.Cookie{{Name: "b", Value: "b", MaxAge: 100}})
+	time.Sleep(50 * time.Millisecond)
+	c.Assert(storage.storeCount(), qt.Equals, 2)
+}
+
+// errorStorage implements Storage but fails to store anything.
+type errorStorage struct {
+	memStorage
+}
+
+func (s *errorStorage) Store(data []byte) error {
+	return errgo.New("cannot store")
+}
+
+func TestAutoSaveError(t *testing.T) {
+	c := qt.New(t)
+	errc := make(chan error, 1)
+	jar, err := New(&Options{
+		PublicSuffixList: testPSL{},
+		Storage:          &errorStorage{},
+		AutoSaveDelay:    time.Millisecond,
+		OnSaveError: func(err error) {
+			errc <- err
+		},
+	})
+	c.Assert(err, qt.Equals, nil)
+	jar.SetCookies(mustParseURL("http://www.host.test"), []*http.Cookie{{Name: "a", Value: "a"}})
+	select {
+	case err := <-errc:
+		c.Assert(err, qt.ErrorMatches, "cannot store")
+	case <-time.After(5 * time.Second):
+		c.Fatalf("timed out waiting for save error")
+	}
+	c.Assert(jar.Close(), qt.ErrorMatches, "cannot store")
+}
+
+func TestAutoSaveDebounce(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	jar, err := New(&Options{
+		PublicSuffixList: testPSL{},
+		Storage:          storage,
+		AutoSaveDelay:    100 * time.Millisecond,
+	})
+	c.Assert(err, qt.Equals, nil)
+	u := mustParseURL("http://www.host.test")
+	// Keep changing the jar for longer than the delay;
+	// each change postpones the save.
+	for i := 0; i < 30; i++ {
+		jar.SetCookies(u, []*http.Cookie{{Name: fmt.Sprint("a", i), Value: "a", MaxAge: 100}})
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(storage.storeCount(), qt.Equals, 0)
+	for a := time.Now(); storage.storeCount() == 0; time.Sleep(time.Millisecond) {
+		if time.Since(a) > 5*time.Second {
+			c.Fatalf("timed out waiting for automatic save")
+		}
+	}
+	c.Assert(jar.Close(), qt.Equals, nil)
+	c.Assert(storage.storeCount(), qt.Equals, 1)
+}
+
+func TestAutoSaveErrorClose(t *testing.T) {
+	c := qt.New(t)
+	done := make(chan error, 1)
+	var jar *Jar
+	jar, err := New(&Options{
+		PublicSuffixList: testPSL{},
+		Storage:          &errorStorage{},
+		AutoSaveDelay:    time.Millisecond,
+		OnSaveError: func(err error) {
+			// Closing the jar from the callback
+			// must not deadlock.
+			done <- jar.Close()
+		},
+	})
+	c.Assert(err, qt.Equals, nil)
+	jar.SetCookies(mustParseURL("http://www.host.test"), []*http.Cookie{{Name: "a", Value: "a"}})
+	select {
+	case err := <-done:
+		c.Assert(err, qt.ErrorMatches, "cannot store")
+	case <-time.After(5 * time.Second):
+		c.Fatalf("timed out waiting for Close")
+	}
+}
+
+func newSessionJar(c *qt.C, storage Storage, persist bool) *Jar {
+	jar, err := New(&Options{
+		PublicSuffixList:      testPSL{},
+		Storage:               storage,
+		PersistSessionCookies: persist,
+	})
+	c.Assert(err, qt.Equals, nil)
+	return jar
+}
+
+func TestPersistSessionCookies(t *testing.T) {
+	c := qt.New(t)
+	for _, persist := range []bool{false, true} {
+		storage := &memStorage{}
+		j0 := newSessionJar(c, storage, persist)
+		now := time.Now()
+		setCookies(j0, "http://www.host.test", []string{"a=1", "b=1; max-age=100"}, now)
+		err := j0.Save()
+		c.Assert(err, qt.Equals, nil)
+
+		j1 := newSessionJar(c, storage, persist)
+		want := "b=1"
+		if persist {
+			want = "a=1 b=1"
+		}
+		c.Assert(allCookies(j1, now), qt.Equals, want)
+	}
+}
+
+func TestEndSession(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	j0 := newSessionJar(c, storage, true)
+	now := time.Now()
+	setCookies(j0, "http://www.host.test", []string{"a=1", "b=1; max-age=100"}, now)
+	setCookies(j0, "http://other.test", []string{"c=1"}, now)
+	err := j0.Save()
+	c.Assert(err, qt.Equals, nil)
+
+	j1 := newSessionJar(c, storage, true)
+	var r eventRecorder
+	j1.Subscribe(r.record)
+	j1.EndSession()
+	c.Assert(allCookies(j1, now), qt.Equals, "b=1")
+	events := r.events
+	sort.Strings(events)
+	c.Assert(events, qt.DeepEquals, []string{"deleted a=1 -", "deleted c=1 -"})
+	err = j1.Save()
+	c.Assert(err, qt.Equals, nil)
+
+	// The removal is saved and takes precedence over
+	// the session cookies in other jars.
+	err = j0.Save()
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(allCookies(j0, now), qt.Equals, "b=1")
+}
+
+func TestMaxSessionLifetime(t *testing.T) {
+	c := qt.New(t)
+	jar, err := New(&Options{
+		PublicSuffixList:   testPSL{},
+		NoPersist:          true,
+		MaxSessionLifetime: time.Hour,
+	})
+	c.Assert(err, qt.Equals, nil)
+	setCookies(jar, "http://www.host.test", []string{"a=1", "b=1; max-age=86400"}, tNow)
+	c.Assert(allCookies(jar, tNow.Add(59*time.Minute)), qt.Equals, "a=1 b=1")
+	c.Assert(allCookies(jar, tNow.Add(61*time.Minute)), qt.Equals, "b=1")
+
+	// Setting the cookie again extends its lifetime.
+	setCookies(jar, "http://www.host.test", []string{"a=2"}, tNow.Add(30*time.Minute))
+	c.Assert(allCookies(jar, tNow.Add(89*time.Minute)), qt.Equals, "a=2 b=1")
+}
+
+func TestEvictKeepsRemovedCookies(t *testing.T) {
+	c := qt.New(t)
+	storage := &memStorage{}
+	j0 := newStorageJar(c, storage)
+	now := time.Now()
+	setCookies(j0, "http://www.host.test", []string{"x=secret; max-age=1000"}, now)
+	c.Assert(j0.Save(), qt.Equals, nil)
+
+	j, err := New(&Options{
+		PublicSuffixList:    testPSL{},
+		Storage:             storage,
+		MaxCookiesPerDomain: 2,
+	})
+	c.Assert(err, qt.Equals, nil)
+	j.RemoveCookie(&http.Cookie{Name: "x", Domain: "www.host.test", Path: "/"})
+	setCookies(j, "http://www.host.test", []string{"a=a; max-age=1000", "b=b; max-age=1000"}, time.Now())
+
+	// Another jar uses x, which makes it the most recently
+	// accessed cookie, and saves it.
+	c.Assert(queryJar(j0, "http://www.host.test", time.Now().Add(time.Second)), qt.Equals, "x=secret")
+	setCookies(j0, "http://www.host.test", []string{"y=y; max-age=1000"}, time.Now().Add(time.Second))
+	c.Assert(j0.Save(), qt.Equals, nil)
+
+	// The removal must win when the jars are merged, even
+	// though y causes one of the other cookies to be evicted.
+	c.Assert(j.Save(), qt.Equals, nil)
+	c.Assert(queryJar(j, "http://www.host.test", time.Now()), qt.Equals, "b=b y=y")
+}
@@ -0,0 +1,86 @@
+#!/usr/bin/env python3
+# Generates the Chromium cookie database fixtures used by chromium_test.go.
+# Run from the testdata directory. Requires the openssl command.
+
+import hashlib
+import os
+import sqlite3
+import subprocess
+
+SCHEMA = """CREATE TABLE cookies(
+    creation_utc INTEGER NOT NULL,
+    host_key TEXT NOT NULL,
+    top_frame_site_key TEXT NOT NULL,
+    name TEXT NOT NULL,
+    value TEXT NOT NULL,
+    encrypted_value BLOB NOT NULL,
+    path TEXT NOT NULL,
+    expires_utc INTEGER NOT NULL,
+    is_secure INTEGER NOT NULL,
+    is_httponly INTEGER NOT NULL,
+    last_access_utc INTEGER NOT NULL,
+    has_expires INTEGER NOT NULL,
+    is_persistent INTEGER NOT NULL,
+    priority INTEGER NOT NULL,
+    samesite INTEGER NOT NULL,
+    source_scheme INTEGER NOT NULL,
+    source_port INTEGER NOT NULL,
+    last_update_utc INTEGER NOT NULL,
+    source_type INTEGER NOT NULL,
+    has_cross_site_ancestor INTEGER NOT NULL)"""
+
+# Microseconds between 1601-01-01 and 1970-01-01.
+EPOCH_DELTA = 11644473600 * 1000000
+
+
+def chromium_time(unix):
+    return unix * 1000000 + EPOCH_DELTA
+
+
+def encrypt(prefix, password, host_key, value, version):
+    key = hashlib.pbkdf2_hmac("sha1", password, b"saltysalt", 1, 16)
+    plaintext = value.encode()
+    if version >= 24:
+        plaintext = hashlib.sha256(host_key.encode()).digest() + plaintext
+    ciphertext = subprocess.run(
+        ["openssl", "enc", "-aes-128-cbc", "-K", key.hex(), "-iv", (b" " * 16).hex()],
+        input=plaintext, stdout=subprocess.PIPE, check=True).stdout
+    return prefix + ciphertext
+
+
+def make(path, version, rows):
+    if os.path.exists(path):
+        os.remove(path)
+    conn = sqlite3.connect(path)
+    conn.execute("CREATE TABLE meta(key LONGVARCHAR NOT NULL UNIQUE PRIMARY KEY, value LONGVARCHAR)")
+    conn.execute("INSERT INTO meta VALUES ('version', ?)", (str(version),))
+    conn.execute(SCHEMA)
+    for r in rows:
+        encrypted = b""
+        value = r.get("value", "")
+        if "encrypt" in r:
+            prefix, password = r["encrypt"]
+            encrypted = encrypt(prefix, password, r["host"], value, version)
+            value = ""
+        conn.execute(
+            "INSERT INTO cookies VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)",
+            (chromium_time(1700000000), r["host"], r.get("site", ""), r["name"], value, encrypted,
+             r.get("path", "/"), chromium_time(r.get("expires", 1893456000)) if r.get("persistent", 1) else 0,
+             r.get("secure", 0), r.get("httponly", 0), chromium_time(1730000000),
+             r.get("persistent", 1), r.get("persistent", 1), 1, r.get("samesite", -1), 2, 443,
+             chromium_time(1720000000), 0, 0))
+    conn.commit()
+    conn.close()
+
+
+ROWS = [
+    {"host": "www.example.com", "name": "plain", "value": "p", "samesite": 2, "secure": 1, "httponly": 1},
+    {"host": ".example.com", "name": "v10", "value": "ten", "encrypt": (b"v10", b"peanuts"), "path": "/app", "samesite": 1},
+    {"host": "www.example.com", "name": "v11", "value": "eleven", "encrypt": (b"v11", b"keyring-secret"), "samesite": 0, "secure": 1},
+    {"host": "www.example.com", "name": "session", "value": "s", "persistent": 0},
+    {"host": "www.example.com", "name": "expired", "value": "e", "expires": 1000000000},
+    {"host": "embed.test", "name": "partitioned", "value": "pt", "site": "https://site.test", "secure": 1},
+]
+
+make("chromium/Cookies", 24, ROWS)
+make("chromium-v10/Cookies", 18, [r for r in ROWS if r["name"] != "v11"])
@@ -0,0 +1,90 @@
+#!/usr/bin/env python3
+# Generates the Firefox cookie database fixtures used by firefox_test.go.
+# Run from the testdata directory.
+
+import os
+import shutil
+import sqlite3
+import tempfile
+
+SCHEMA = """CREATE TABLE moz_cookies (
+    id INTEGER PRIMARY KEY,
+    originAttributes TEXT NOT NULL DEFAULT '',
+    name TEXT,
+    value TEXT,
+    host TEXT,
+    path TEXT,
+    expiry INTEGER,
+    lastAccessed INTEGER,
+    creationTime INTEGER,
+    isSecure INTEGER,
+    isHttpOnly INTEGER,
+    inBrowserElement INTEGER DEFAULT 0,
+    sameSite INTEGER DEFAULT 0,
+    CONSTRAINT moz_uniqueid UNIQUE (name, host, path, originAttributes)
+)"""
+
+COLUMNS = "originAttributes, name, value, host, path, expiry, lastAccessed, creationTime, isSecure, isHttpOnly, sameSite"
+
+CREATED = 1700000000000000
+ACCESSED = 1730000000000000
+
+
+def insert(conn, attrs, name, value, host, path, expiry, secure=0, httponly=0, samesite=0, extra=None):
+    cols = COLUMNS
+    vals = [attrs, name, value, host, path, expiry, ACCESSED, CREATED, secure, httponly, samesite]
+    if extra:
+        for k, v in extra.items():
+            cols += ", " + k
+            vals.append(v)
+    conn.execute("INSERT INTO moz_cookies (%s) VALUES (%s)" % (cols, ", ".join("?" * len(vals))), vals)
+
+
+def make_main(path):
+    if os.path.exists(path):
+        os.remove(path)
+    conn = sqlite3.connect(path)
+    conn.execute("PRAGMA page_size = 1024")
+    conn.execute(SCHEMA)
+    # Rows written before the later columns were added.
+    for i in range(80):
+        insert(conn, "", "n", "v%d" % i, "filler%d.test" % i, "/", 1893456000)
+    conn.execute("ALTER TABLE moz_cookies ADD COLUMN rawSameSite INTEGER DEFAULT 0")
+    conn.execute("ALTER TABLE moz_cookies ADD COLUMN schemeMap INTEGER DEFAULT 0")
+    conn.execute("ALTER TABLE moz_cookies ADD COLUMN isPartitionedAttributeSet INTEGER DEFAULT 0")
+    insert(conn, "", "host", "abc", "www.example.com", "/", 1893456000, secure=1, httponly=1, samesite=2)
+    insert(conn, "", "domain", "d", ".example.com", "/app", 1893456000000, samesite=1)
+    insert(conn, "", "expired", "e", "www.example.com", "/", 1000000000)
+    insert(conn, "^userContextId=2", "container", "c", "www.example.com", "/", 1893456000)
+    insert(conn, "^privateBrowsingId=1", "private", "p", "www.example.com", "/", 1893456000)
+    insert(conn, "^partitionKey=%28https%2Csite.test%29", "partitioned", "pt", "embed.test", "/", 1893456000,
+           secure=1, extra={"isPartitionedAttributeSet": 1})
+    insert(conn, "", "long", "x" * 3000, "long.test", "/", 1893456000)
+    conn.commit()
+    conn.close()
+
+
+def make_wal(dir):
+    os.makedirs(dir, exist_ok=True)
+    tmp = tempfile.mkdtemp()
+    src = os.path.join(tmp, "cookies.sqlite")
+    conn = sqlite3.connect(src)
+    conn.execute("PRAGMA page_size = 1024")
+    conn.execute("PRAGMA journal_mode = WAL")
+    conn.execute("PRAGMA wal_autocheckpoint = 0")
+    conn.execute(SCHEMA)
+    insert(conn, "", "a", "1", "www.example.com", "/", 1893456000)
+    conn.commit()
+    conn.execute("PRAGMA wal_checkpoint(TRUNCATE)")
+    conn.execute("UPDATE moz_cookies SET value = '2' WHERE name = 'a'")
+    conn.commit()
+    insert(conn, "", "b", "3", "www.example.com", "/", 1893456000)
+    conn.commit()
+    for name in ["cookies.sqlite", "cookies.sqlite-wal"]:
+        shutil.copy(os.path.join(tmp, name), os.path.join(dir, name))
+    conn.close()
+    shutil.rmtree(tmp)
+
+
+make_main("firefox/cookies.sqlite")
+make_wal("firefox-wal")
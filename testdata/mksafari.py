@@ -0,0 +1,89 @@
+#!/usr/bin/env python3
+# Generates the Safari Cookies.binarycookies fixture used by
+# safari_test.go. Run from the testdata directory.
+#
+# The format is undocumented; this follows the layout written by
+# CFNetwork on macOS.
+
+import plistlib
+import struct
+
+MAC_EPOCH = 978307200
+
+SECURE = 1
+HTTPONLY = 4
+
+
+def mac_time(unix):
+    return unix - MAC_EPOCH
+
+
+def record(domain, name, path, value, expires, created, flags=0, port=None, comment=None):
+    # A port, if any, is held just after the fixed fields,
+    # and a comment is held with the other strings. The
+    # reader ignores both.
+    extra = b""
+    if port is not None:
+        extra = struct.pack("<H", port)
+    start = 56 + len(extra)
+    strings = b""
+    offsets = []
+    for s in [domain, name, path, value, comment]:
+        if s is None:
+            offsets.append(0)
+            continue
+        offsets.append(start + len(strings))
+        strings += s.encode() + b"\0"
+    size = start + len(strings)
+    header = struct.pack("<IIII", size, 0, flags, int(port is not None))
+    header += struct.pack("<IIII", *offsets[:4])
+    header += struct.pack("<II", offsets[4], 0)
+    header += struct.pack("<dd", mac_time(expires), mac_time(created))
+    return header + extra + strings
+
+
+def page(records):
+    header_size = 4 + 4 + 4 * len(records) + 4
+    offsets = []
+    body = b""
+    for r in records:
+        offsets.append(header_size + len(body))
+        body += r
+    data = b"\x00\x00\x01\x00" + struct.pack("<I", len(records))
+    data += b"".join(struct.pack("<I", o) for o in offsets)
+    data += b"\x00\x00\x00\x00"
+    return data + body
+
+
+def checksum(page):
+    return sum(page[i] for i in range(0, len(page), 4))
+
+
+def binarycookies(pages):
+    data = b"cook" + struct.pack(">I", len(pages))
+    data += b"".join(struct.pack(">I", len(p)) for p in pages)
+    data += b"".join(pages)
+    data += struct.pack(">I", sum(checksum(p) for p in pages) & 0xffffffff)
+    data += bytes.fromhex("071720050000004b")
+    data += plistlib.dumps({"NSHTTPCookieAcceptPolicy": 2}, fmt=plistlib.FMT_BINARY)
+    return data
+
+
+CREATED = 1700000000.5
+EXPIRES = 1893456000
+
+pages = [
+    page([
+        record("www.example.com", "host", "/", "abc", EXPIRES, CREATED, SECURE | HTTPONLY),
+        record(".example.com", "domain", "/app", "d", EXPIRES, CREATED),
+        record("www.example.com", "expired", "/", "e", 1000000000, CREATED),
+    ]),
+    page([
+        record(".co.uk", "publicsuffix", "/", "p", EXPIRES, CREATED),
+        record("other.test", "empty", "", "", EXPIRES, 1710000000.25, SECURE),
+        record("other.test", "port", "/", "8080", EXPIRES, CREATED, port=8080, comment="a comment"),
+    ]),
+]
+
+with open("safari/Cookies.binarycookies", "wb") as f:
+    f.write(binarycookies(pages))
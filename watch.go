@@ -0,0 +1,128 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"bytes"
+	"log"
+	"time"
+
+	"gopkg.in/errgo.v1"
+)
+
+// watchPollInterval holds the interval at which storage is
+// polled for changes when change notifications are not available.
+var watchPollInterval = time.Second
+
+// notifier is implemented by types that notify when
+// a file may have changed.
+type notifier interface {
+	// C returns a channel that receives a value
+	// when the file may have changed.
+	C() <-chan struct{}
+
+	// Close stops the notifications.
+	Close() error
+}
+
+// startWatching starts a goroutine that merges changes made to
+// j.storage by other jars into j. The goroutine is stopped by
+// stopWatching.
+func (j *Jar) startWatching() {
+	var n notifier
+	if fs, ok := j.storage.(*fileStorage); ok {
+		fn, err := newFileNotifier(fs.filename)
+		if err == nil {
+			n = fn
+		}
+	}
+	j.stopWatch = make(chan struct{})
+	j.watchDone = make(chan struct{})
+	go j.watch(n, j.stopWatch, j.watchDone)
+}
+
+// stopWatching stops the goroutine started by startWatching
+// and waits for it to finish. It does nothing if the goroutine
+// is not running.
+func (j *Jar) stopWatching() {
+	j.mu.Lock()
+	stop, done := j.stopWatch, j.watchDone
+	j.stopWatch, j.watchDone = nil, nil
+	j.mu.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// watch reloads j's cookies whenever n notifies of a change, or
+// periodically if n is nil, until stop is closed.
+func (j *Jar) watch(n notifier, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	var changes <-chan struct{}
+	var poll <-chan time.Time
+	if n != nil {
+		defer n.Close()
+		changes = n.C()
+	} else {
+		ticker := time.NewTicker(watchPollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+	for {
+		select {
+		case <-changes:
+		case <-poll:
+		case <-stop:
+			return
+		}
+		if err := j.reload(); err != nil {
+			log.Printf("cannot reload cookies: %v", err)
+		}
+	}
+}
+
+// reload merges any changes made to j.storage since it was last
+// loaded or saved into j.
+func (j *Jar) reload() error {
+	if vs, ok := j.storage.(versionedStorage); ok {
+		// Avoid acquiring the lock when we can cheaply
+		// tell that nothing has changed.
+		v, err := vs.version()
+		if err != nil {
+			return errgo.Mask(err)
+		}
+		j.mu.Lock()
+		unchanged := v != nil && j.stored.known && v == j.stored.version
+		j.mu.Unlock()
+		if unchanged {
+			return nil
+		}
+	}
+	defer j.notify()
+	locked, err := j.storage.Lock()
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	defer locked.Close()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	data, stored, changed, err := j.loadChanged()
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	if !changed {
+		return nil
+	}
+	data, err = j.decodeStored(data)
+	if err != nil {
+		return errgo.Mask(err)
+	}
+	if err := j.mergeFrom(bytes.NewReader(data)); err != nil {
+		return errgo.Mask(err)
+	}
+	j.stored = stored
+	return nil
+}
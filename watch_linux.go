@@ -0,0 +1,85 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build linux
+// +build linux
+
+package cookiejar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"gopkg.in/errgo.v1"
+)
+
+// inotifyNotifier implements notifier using inotify(7).
+type inotifyNotifier struct {
+	f *os.File
+	c chan struct{}
+}
+
+// newFileNotifier returns a notifier that notifies when the
+// named file is written, replaced or removed.
+//
+// The directory containing the file is watched rather than the file
+// itself because the file is replaced on each save.
+func newFileNotifier(filename string) (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, errgo.Notef(err, "cannot initialize inotify")
+	}
+	const mask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_CREATE | syscall.IN_DELETE
+	if _, err := syscall.InotifyAddWatch(fd, filepath.Dir(filename), mask); err != nil {
+		syscall.Close(fd)
+		return nil, errgo.Notef(err, "cannot watch directory")
+	}
+	// The file descriptor is non-blocking, so
+	// closing f will interrupt any pending read.
+	n := &inotifyNotifier{
+		f: os.NewFile(uintptr(fd), "inotify"),
+		c: make(chan struct{}, 1),
+	}
+	go n.run(filepath.Base(filename))
+	return n, nil
+}
+
+// C implements notifier.C.
+func (n *inotifyNotifier) C() <-chan struct{} {
+	return n.c
+}
+
+// Close implements notifier.Close.
+func (n *inotifyNotifier) Close() error {
+	return n.f.Close()
+}
+
+// run reads events from n.f, notifying on n.c about those that
+// refer to the given file name, until n.f is closed.
+func (n *inotifyNotifier) run(name string) {
+	buf := make([]byte, 64*1024)
+	for {
+		nr, err := n.f.Read(buf)
+		if err != nil {
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= nr; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			offset += syscall.SizeofInotifyEvent
+			eventName := strings.TrimRight(string(buf[offset:offset+int(event.Len)]), "\x00")
+			offset += int(event.Len)
+			if eventName != name {
+				continue
+			}
+			select {
+			case n.c <- struct{}{}:
+			default:
+				// There is already a notification pending.
+			}
+		}
+	}
+}
@@ -0,0 +1,19 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+// +build !linux
+
+package cookiejar
+
+import (
+	"gopkg.in/errgo.v1"
+)
+
+// newFileNotifier returns an error because file change
+// notifications are not implemented on this platform,
+// so the storage will be polled instead.
+func newFileNotifier(filename string) (notifier, error) {
+	return nil, errgo.New("file change notifications not supported")
+}
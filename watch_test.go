@@ -0,0 +1,103 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cookiejar
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+)
+
+func TestWatchFile(t *testing.T) {
+	c := qt.New(t)
+	d, err := ioutil.TempDir("", "")
+	c.Assert(err, qt.Equals, nil)
+	defer os.RemoveAll(d)
+	file := filepath.Join(d, "cookies")
+
+	watcher, err := New(&Options{
+		PublicSuffixList: testPSL{},
+		Filename:         file,
+		Watch:            true,
+	})
+	c.Assert(err, qt.Equals, nil)
+	defer watcher.Close()
+
+	for _, test := range []struct {
+		cookie string
+		want   string
+	}{
+		{"a=a; max-age=100", "a=a"},
+		{"b=b; max-age=100", "a=a b=b"},
+	} {
+		j := newTestJar(file)
+		setCookies(j, "http://www.host.test", []string{test.cookie}, time.Now())
+		c.Assert(j.Save(), qt.Equals, nil)
+		waitForCookies(c, watcher, "http://www.host.test", test.want)
+	}
+}
+
+func TestWatchPoll(t *testing.T) {
+	c := qt.New(t)
+	defer patchWatchPollInterval(10 * time.Millisecond)()
+	storage := &memStorage{}
+	watcher, err := New(&Options{
+		PublicSuffixList: testPSL{},
+		Storage:          storage,
+		Watch:            true,
+	})
+	c.Assert(err, qt.Equals, nil)
+	defer watcher.Close()
+
+	j := newStorageJar(c, storage)
+	setCookies(j, "http://www.host.test", []string{"a=a; max-age=100"}, time.Now())
+	c.Assert(j.Save(), qt.Equals, nil)
+	waitForCookies(c, watcher, "http://www.host.test", "a=a")
+}
+
+func TestWatchClose(t *testing.T) {
+	c := qt.New(t)
+	defer patchWatchPollInterval(10 * time.Millisecond)()
+	storage := &memStorage{}
+	watcher, err := New(&Options{
+		PublicSuffixList: testPSL{},
+		Storage:          storage,
+		Watch:            true,
+	})
+	c.Assert(err, qt.Equals, nil)
+	c.Assert(watcher.Close(), qt.Equals, nil)
+	// Closing twice is fine.
+	c.Assert(watcher.Close(), qt.Equals, nil)
+
+	j := newStorageJar(c, storage)
+	setCookies(j, "http://www.host.test", []string{"a=a; max-age=100"}, time.Now())
+	c.Assert(j.Save(), qt.Equals, nil)
+	time.Sleep(50 * time.Millisecond)
+	c.Assert(queryJar(watcher, "http://www.host.test", time.Now()), qt.Equals, "")
+}
+
+// waitForCookies waits until a query for the given URL
+// in jar returns the wanted cookies.
+func waitForCookies(c *qt.C, jar *Jar, u string, want string) {
+	var got string
+	for a := time.Now(); time.Since(a) < 5*time.Second; time.Sleep(time.Millisecond) {
+		if got = queryJar(jar, u, time.Now()); got == want {
+			return
+		}
+	}
+	c.Fatalf("timed out waiting for cookies; got %q want %q", got, want)
+}
+
+func patchWatchPollInterval(d time.Duration) func() {
+	old := watchPollInterval
+	watchPollInterval = d
+	return func() {
+		watchPollInterval = old
+	}
+}